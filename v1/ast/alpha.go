@@ -0,0 +1,211 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"maps"
+	"strconv"
+)
+
+// CompareAlphaEquiv compares a and b like Compare, but considers
+// comprehensions and bodies equal if they only differ in the names of the
+// variables they declare. For example, [x | some x in xs] and
+// [y | some y in xs] compare equal, while [x | some x in xs] and
+// [x | some x in ys] do not.
+//
+// A variable is considered declared by a body if it is introduced by a some
+// declaration, on the left-hand side of an assignment (:=), as the key or value
+// of an every expression, or if it is a wildcard (_). Declarations in nested
+// comprehensions shadow outer ones. All other variables are free: they may
+// refer to the enclosing scope and are never renamed, so comprehensions that
+// only differ in the names of free variables are not equal.
+func CompareAlphaEquiv(a, b any) int {
+	return Compare(alphaCanonical(a), alphaCanonical(b))
+}
+
+// alphaCanonical returns a copy of x where all declared variables are renamed
+// based on the order in which they're declared.
+func alphaCanonical(x any) any {
+	r := &alphaRenamer{}
+	switch x := x.(type) {
+	case *Term:
+		return r.term(x, map[Var]Var{})
+	case Value:
+		return r.value(x, map[Var]Var{})
+	case Body:
+		return r.body(x, r.declare(x, map[Var]Var{}))
+	case *Expr:
+		return r.expr(x, r.declare(Body{x}, map[Var]Var{}))
+	}
+	return x
+}
+
+type alphaRenamer struct {
+	n int
+}
+
+func (r *alphaRenamer) fresh() Var {
+	v := Var(WildcardPrefix + "alpha" + strconv.Itoa(r.n))
+	r.n++
+	return v
+}
+
+// declare returns a new scope that extends scope with the variables declared
+// in body.
+func (r *alphaRenamer) declare(body Body, scope map[Var]Var) map[Var]Var {
+	inner := maps.Clone(scope)
+	add := func(v Var) {
+		inner[v] = r.fresh()
+	}
+	for _, expr := range body {
+		switch {
+		case expr.IsAssignment():
+			alphaPatternVars(expr.Operand(0), add)
+		default:
+			if decl, ok := expr.Terms.(*SomeDecl); ok {
+				for _, sym := range decl.Symbols {
+					switch v := sym.Value.(type) {
+					case Var:
+						add(v)
+					case Call:
+						// some x in xs / some k, v in xs: the last operand is
+						// the collection being iterated.
+						for _, t := range v[1 : len(v)-1] {
+							alphaPatternVars(t, add)
+						}
+					}
+				}
+			}
+		}
+	}
+	return inner
+}
+
+// alphaPatternVars calls f for each variable in the pattern t, e.g. the
+// left-hand side of an assignment.
+func alphaPatternVars(t *Term, f func(Var)) {
+	if t == nil {
+		return
+	}
+	switch v := t.Value.(type) {
+	case Var:
+		f(v)
+	case *Array:
+		for _, elem := range v.elems {
+			alphaPatternVars(elem, f)
+		}
+	case Object:
+		v.Foreach(func(_, val *Term) {
+			alphaPatternVars(val, f)
+		})
+	}
+}
+
+func (r *alphaRenamer) term(t *Term, scope map[Var]Var) *Term {
+	if t == nil {
+		return nil
+	}
+	cpy := *t
+	cpy.Value = r.value(t.Value, scope)
+	return &cpy
+}
+
+func (r *alphaRenamer) terms(ts []*Term, scope map[Var]Var) []*Term {
+	cpy := make([]*Term, len(ts))
+	for i := range ts {
+		cpy[i] = r.term(ts[i], scope)
+	}
+	return cpy
+}
+
+func (r *alphaRenamer) value(v Value, scope map[Var]Var) Value {
+	switch v := v.(type) {
+	case Var:
+		if n, ok := scope[v]; ok {
+			return n
+		}
+		if v.IsWildcard() {
+			// Wildcards are unique, so they can be declared on first use.
+			scope[v] = r.fresh()
+			return scope[v]
+		}
+		return v
+	case Ref:
+		return Ref(r.terms(v, scope))
+	case *Array:
+		return NewArray(r.terms(v.elems, scope)...)
+	case Object:
+		obj := NewObject()
+		v.Foreach(func(k, val *Term) {
+			obj.Insert(r.term(k, scope), r.term(val, scope))
+		})
+		return obj
+	case Set:
+		set := NewSet()
+		v.Foreach(func(t *Term) {
+			set.Add(r.term(t, scope))
+		})
+		return set
+	case Call:
+		return Call(r.terms(v, scope))
+	case *ArrayComprehension:
+		inner := r.declare(v.Body, scope)
+		body := r.body(v.Body, inner)
+		return &ArrayComprehension{Term: r.term(v.Term, inner), Body: body}
+	case *ObjectComprehension:
+		inner := r.declare(v.Body, scope)
+		body := r.body(v.Body, inner)
+		return &ObjectComprehension{Key: r.term(v.Key, inner), Value: r.term(v.Value, inner), Body: body}
+	case *SetComprehension:
+		inner := r.declare(v.Body, scope)
+		body := r.body(v.Body, inner)
+		return &SetComprehension{Term: r.term(v.Term, inner), Body: body}
+	}
+	return v
+}
+
+func (r *alphaRenamer) body(body Body, scope map[Var]Var) Body {
+	cpy := make(Body, len(body))
+	for i := range body {
+		cpy[i] = r.expr(body[i], scope)
+	}
+	return cpy
+}
+
+func (r *alphaRenamer) expr(expr *Expr, scope map[Var]Var) *Expr {
+	cpy := &Expr{
+		Index:     expr.Index,
+		Generated: expr.Generated,
+		Negated:   expr.Negated,
+		Location:  expr.Location,
+	}
+	switch ts := expr.Terms.(type) {
+	case *Term:
+		cpy.Terms = r.term(ts, scope)
+	case []*Term:
+		cpy.Terms = r.terms(ts, scope)
+	case *SomeDecl:
+		cpy.Terms = &SomeDecl{Symbols: r.terms(ts.Symbols, scope), Location: ts.Location}
+	case *Every:
+		inner := maps.Clone(scope)
+		alphaPatternVars(ts.Key, func(v Var) { inner[v] = r.fresh() })
+		alphaPatternVars(ts.Value, func(v Var) { inner[v] = r.fresh() })
+		inner = r.declare(ts.Body, inner)
+		cpy.Terms = &Every{
+			Key:      r.term(ts.Key, inner),
+			Value:    r.term(ts.Value, inner),
+			Domain:   r.term(ts.Domain, scope),
+			Body:     r.body(ts.Body, inner),
+			Location: ts.Location,
+		}
+	}
+	if len(expr.With) > 0 {
+		cpy.With = make([]*With, len(expr.With))
+		for i, w := range expr.With {
+			cpy.With[i] = &With{Target: r.term(w.Target, scope), Value: r.term(w.Value, scope), Location: w.Location}
+		}
+	}
+	return cpy
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "testing"
+
+func TestCompareAlphaEquiv(t *testing.T) {
+	tests := []struct {
+		note string
+		a, b string
+		exp  bool
+	}{
+		{"scalars", `1`, `1`, true},
+		{"some in", `[x | some x in xs]`, `[y | some y in xs]`, true},
+		{"some in key value", `{k: v | some k, v in xs}`, `{a: b | some a, b in xs}`, true},
+		{"some in swapped", `{k: v | some k, v in xs}`, `{v: k | some k, v in xs}`, false},
+		{"some decl", `{x | some x; xs[x]}`, `{y | some y; xs[y]}`, true},
+		{"assignment", `[y | x := xs[_]; y := x + 1]`, `[b | a := xs[_]; b := a + 1]`, true},
+		{"assignment pattern", `[x | [x, _] := xs[_]]`, `[y | [y, _] := xs[_]]`, true},
+		{"wildcards", `[x | x := xs[_]]`, `[x | x := xs[_]]`, true},
+		{"free vars differ", `[x | some x in xs]`, `[x | some x in ys]`, false},
+		{"free vs bound", `[x | y := xs[_]]`, `[y | x := xs[_]]`, false},
+		{"nested shadowing", `[x | some x in xs; y := [x | some x in ys]]`, `[a | some a in xs; b := [c | some c in ys]]`, true},
+		{"nested outer ref", `[x | some x in xs; y := [z | some z in x]]`, `[a | some a in xs; b := [c | some c in c]]`, false},
+		{"every", `[x | some x in xs; every y in x { y > 0 }]`, `[a | some a in xs; every b in a { b > 0 }]`, true},
+		{"body differs", `[x | some x in xs; x > 0]`, `[y | some y in xs; y > 1]`, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			a := MustParseTerm(tc.a)
+			b := MustParseTerm(tc.b)
+			result := CompareAlphaEquiv(a, b)
+			if (result == 0) != tc.exp {
+				t.Fatalf("expected equal to be %v for %v and %v but got %d", tc.exp, a, b, result)
+			}
+			if -result != CompareAlphaEquiv(b, a) {
+				t.Fatalf("expected antisymmetric result for %v and %v", a, b)
+			}
+		})
+	}
+}
+
+func TestCompareAlphaEquivBody(t *testing.T) {
+	a := MustParseBody(`some x; p[x]; y := x; y > 1`)
+	b := MustParseBody(`some a; p[a]; b := a; b > 1`)
+	if CompareAlphaEquiv(a, b) != 0 {
+		t.Fatalf("expected %v and %v to be alpha equivalent", a, b)
+	}
+	if Compare(a, b) == 0 {
+		t.Fatalf("expected %v and %v not to be equal", a, b)
+	}
+
+	c := MustParseBody(`some a; p[a]; b := a; c > 1`)
+	if CompareAlphaEquiv(a, c) == 0 {
+		t.Fatalf("expected %v and %v not to be alpha equivalent", a, c)
+	}
+}
+
+func TestCompareAlphaEquivDoesNotMutate(t *testing.T) {
+	a := MustParseTerm(`[x | some x in xs]`)
+	exp := a.Copy()
+	CompareAlphaEquiv(a, MustParseTerm(`[y | some y in xs]`))
+	if !a.Equal(exp) {
+		t.Fatalf("expected %v to be unchanged but got %v", exp, a)
+	}
+}
@@ -165,6 +165,75 @@ func (a *Annotations) Compare(other *Annotations) int {
 	return 0
 }
 
+// AnnotationField identifies a field of Annotations to compare with
+// CompareAnnotationBy.
+type AnnotationField int
+
+const (
+	// AnnotationFieldScope is the Scope field.
+	AnnotationFieldScope AnnotationField = iota
+	// AnnotationFieldTitle is the Title field.
+	AnnotationFieldTitle
+	// AnnotationFieldDescription is the Description field.
+	AnnotationFieldDescription
+	// AnnotationFieldOrganizations is the Organizations field.
+	AnnotationFieldOrganizations
+	// AnnotationFieldRelatedResources is the RelatedResources field.
+	AnnotationFieldRelatedResources
+	// AnnotationFieldAuthors is the Authors field.
+	AnnotationFieldAuthors
+	// AnnotationFieldSchemas is the Schemas field.
+	AnnotationFieldSchemas
+	// AnnotationFieldEntrypoint is the Entrypoint field.
+	AnnotationFieldEntrypoint
+	// AnnotationFieldCustom is the Custom field.
+	AnnotationFieldCustom
+)
+
+// CompareAnnotationBy compares a and b by a single field, ordered like
+// Annotations.Compare orders it. Missing and empty fields sort first, e.g. an
+// empty title before any other title, and no authors before any authors. Nil
+// annotations sort before all others. CompareAnnotationBy panics if field is
+// not one of the AnnotationField constants.
+func CompareAnnotationBy(a, b *Annotations, field AnnotationField) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch field {
+	case AnnotationFieldScope:
+		return scopeCompare(a.Scope, b.Scope)
+	case AnnotationFieldTitle:
+		return strings.Compare(a.Title, b.Title)
+	case AnnotationFieldDescription:
+		return strings.Compare(a.Description, b.Description)
+	case AnnotationFieldOrganizations:
+		return compareStringLists(a.Organizations, b.Organizations)
+	case AnnotationFieldRelatedResources:
+		return compareRelatedResources(a.RelatedResources, b.RelatedResources)
+	case AnnotationFieldAuthors:
+		return compareAuthors(a.Authors, b.Authors)
+	case AnnotationFieldSchemas:
+		return compareSchemas(a.Schemas, b.Schemas)
+	case AnnotationFieldEntrypoint:
+		switch {
+		case a.Entrypoint == b.Entrypoint:
+			return 0
+		case a.Entrypoint:
+			return 1
+		}
+		return -1
+	case AnnotationFieldCustom:
+		return util.Compare(a.Custom, b.Custom)
+	}
+	panic(fmt.Sprintf("illegal annotation field: %d", field))
+}
+
 // GetTargetPath returns the path of the node these Annotations are applied to (the target)
 func (a *Annotations) GetTargetPath() Ref {
 	switch n := a.node.(type) {
@@ -1195,3 +1195,59 @@ func schemaAnnotationFromMap(path string, def map[string]any) *SchemaAnnotation
 	var p any = def
 	return &SchemaAnnotation{Path: MustParseRef(path), Definition: &p}
 }
+
+func TestCompareAnnotationBy(t *testing.T) {
+	empty := &Annotations{}
+	tests := []struct {
+		field AnnotationField
+		a, b  *Annotations
+		exp   int
+	}{
+		{AnnotationFieldScope, empty, &Annotations{Scope: annotationScopePackage}, -1},
+		{AnnotationFieldScope, &Annotations{Scope: annotationScopeDocument}, &Annotations{Scope: annotationScopePackage}, -1},
+		{AnnotationFieldScope, &Annotations{Scope: annotationScopeSubpackages}, &Annotations{Scope: annotationScopeRule}, -1},
+		{AnnotationFieldTitle, empty, &Annotations{Title: "a"}, -1},
+		{AnnotationFieldTitle, &Annotations{Title: "a"}, &Annotations{Title: "b", Scope: annotationScopePackage}, -1},
+		{AnnotationFieldTitle, &Annotations{Title: "a"}, &Annotations{Title: "a", Description: "b"}, 0},
+		{AnnotationFieldDescription, empty, &Annotations{Description: "a"}, -1},
+		{AnnotationFieldDescription, &Annotations{Description: "b"}, &Annotations{Description: "a"}, 1},
+		{AnnotationFieldOrganizations, empty, &Annotations{Organizations: []string{"a"}}, -1},
+		{AnnotationFieldOrganizations, &Annotations{Organizations: []string{}}, empty, 0},
+		{AnnotationFieldOrganizations, &Annotations{Organizations: []string{"a"}}, &Annotations{Organizations: []string{"b"}}, -1},
+		{AnnotationFieldRelatedResources, empty, &Annotations{RelatedResources: []*RelatedResourceAnnotation{{Ref: mustParseURL("https://a.example.com")}}}, -1},
+		{AnnotationFieldRelatedResources,
+			&Annotations{RelatedResources: []*RelatedResourceAnnotation{{Ref: mustParseURL("https://b.example.com")}}},
+			&Annotations{RelatedResources: []*RelatedResourceAnnotation{{Ref: mustParseURL("https://a.example.com")}}}, 1},
+		{AnnotationFieldAuthors, empty, &Annotations{Authors: []*AuthorAnnotation{{Name: "a"}}}, -1},
+		{AnnotationFieldAuthors, &Annotations{Authors: []*AuthorAnnotation{{Name: "a"}}}, &Annotations{Authors: []*AuthorAnnotation{{Name: "a", Email: "a@example.com"}}}, -1},
+		{AnnotationFieldSchemas, empty, &Annotations{Schemas: []*SchemaAnnotation{{Path: MustParseRef("input")}}}, -1},
+		{AnnotationFieldSchemas, &Annotations{Schemas: []*SchemaAnnotation{{Path: MustParseRef("input.a")}}}, &Annotations{Schemas: []*SchemaAnnotation{{Path: MustParseRef("input.b")}}}, -1},
+		{AnnotationFieldEntrypoint, empty, &Annotations{Entrypoint: true}, -1},
+		{AnnotationFieldEntrypoint, &Annotations{Entrypoint: true, Title: "a"}, &Annotations{Entrypoint: true, Title: "b"}, 0},
+		{AnnotationFieldCustom, empty, &Annotations{Custom: map[string]any{"a": 1}}, -1},
+		{AnnotationFieldCustom, &Annotations{Custom: map[string]any{}}, empty, 0},
+		{AnnotationFieldCustom, &Annotations{Custom: map[string]any{"a": 1}}, &Annotations{Custom: map[string]any{"a": 2}}, -1},
+	}
+
+	for _, tc := range tests {
+		if act := CompareAnnotationBy(tc.a, tc.b, tc.field); act != tc.exp {
+			t.Errorf("Expected %d for field %d, %v and %v but got %d", tc.exp, tc.field, tc.a, tc.b, act)
+		}
+		if act := CompareAnnotationBy(tc.b, tc.a, tc.field); act != -tc.exp {
+			t.Errorf("Expected %d for field %d, %v and %v but got %d", -tc.exp, tc.field, tc.b, tc.a, act)
+		}
+		if act := CompareAnnotationBy(nil, tc.a, tc.field); act != -1 {
+			t.Errorf("Expected nil to sort first for field %d but got %d", tc.field, act)
+		}
+		if act := CompareAnnotationBy(nil, nil, tc.field); act != 0 {
+			t.Errorf("Expected nil to equal nil for field %d but got %d", tc.field, act)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("Expected panic for unknown field")
+		}
+	}()
+	CompareAnnotationBy(empty, empty, AnnotationField(-1))
+}
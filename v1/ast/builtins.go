@@ -346,6 +346,7 @@ var Equality = &Builtin{
 		types.Args(types.A, types.A),
 		types.B,
 	),
+	commutative: true,
 }
 
 /**
@@ -459,6 +460,7 @@ var NotEqual = &Builtin{
 		),
 		types.Named("result", types.B).Description("true if `x` is not equal to `y`; false otherwise"),
 	),
+	commutative: true,
 }
 
 // Equal represents the "==" comparison operator.
@@ -473,6 +475,7 @@ var Equal = &Builtin{
 		),
 		types.Named("result", types.B).Description("true if `x` is equal to `y`; false otherwise"),
 	),
+	commutative: true,
 }
 
 /**
@@ -491,7 +494,8 @@ var Plus = &Builtin{
 		),
 		types.Named("z", types.N).Description("the sum of `x` and `y`"),
 	),
-	Categories: number,
+	Categories:  number,
+	commutative: true,
 }
 
 var Minus = &Builtin{
@@ -519,7 +523,8 @@ var Multiply = &Builtin{
 		),
 		types.Named("z", types.N).Description("the product of `x` and `y`"),
 	),
-	Categories: number,
+	Categories:  number,
+	commutative: true,
 }
 
 var Divide = &Builtin{
@@ -690,7 +695,8 @@ var And = &Builtin{
 		),
 		types.Named("z", types.SetOfAny).Description("the intersection of `x` and `y`"),
 	),
-	Categories: sets,
+	Categories:  sets,
+	commutative: true,
 }
 
 // Or performs a union operation on sets.
@@ -705,7 +711,8 @@ var Or = &Builtin{
 		),
 		types.Named("z", types.SetOfAny).Description("the union of `x` and `y`"),
 	),
-	Categories: sets,
+	Categories:  sets,
+	commutative: true,
 }
 
 var Intersection = &Builtin{
@@ -3338,6 +3345,7 @@ type Builtin struct {
 	Infix            string          `json:"infix,omitempty"`    // Unique name of infix operator. Default should be unset.
 	Relation         bool            `json:"relation,omitempty"` // Indicates if the built-in acts as a relation.
 	deprecated       bool            // Indicates if the built-in has been deprecated.
+	commutative      bool            // Indicates if the order of the first two arguments does not matter.
 	Nondeterministic bool            `json:"nondeterministic,omitempty"` // Indicates if the built-in returns non-deterministic results.
 }
 
@@ -3366,6 +3374,12 @@ func (b *Builtin) IsDeprecated() bool {
 	return b.deprecated
 }
 
+// IsCommutative returns true if swapping the first two arguments of the Builtin
+// function does not change its result, e.g. for "==" and "+".
+func (b *Builtin) IsCommutative() bool {
+	return b.commutative
+}
+
 // IsDeterministic returns true if the Builtin function returns non-deterministic results.
 func (b *Builtin) IsNondeterministic() bool {
 	return b.Nondeterministic
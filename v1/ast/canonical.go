@@ -0,0 +1,309 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Canonical returns a deterministic encoding of v such that values that
+// compare equal under Compare are encoded to identical bytes, and values that
+// do not compare equal are encoded differently. This makes the result suitable
+// for content-addressed storage.
+//
+// The encoding is compact Rego syntax that parses back into a value equal to
+// v: object keys and set elements are sorted by Compare, and numbers are
+// normalized by their numeric value, so 1, 1.0 and 1e0 are all encoded as 1.
+// Numbers are written in plain decimal notation unless their exponent is
+// large, in which case scientific notation is used (e.g. 1e21).
+//
+// Only null, booleans, numbers, strings, arrays, objects and sets are
+// supported. Canonical returns an *UnsupportedValueError for other values, and
+// an error for non-finite numbers and strings that are not valid UTF-8.
+func Canonical(v Value) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := canonicalWrite(&buf, v, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalWrite writes the canonical encoding of v to buf. Values that have
+// no canonical encoding are passed to fallback, or result in an error if
+// fallback is nil.
+func canonicalWrite(buf *bytes.Buffer, v Value, fallback func(*bytes.Buffer, Value) error) error {
+	switch v := v.(type) {
+	case Null:
+		buf.WriteString("null")
+	case Boolean:
+		buf.WriteString(strconv.FormatBool(bool(v)))
+	case Number:
+		s, err := canonicalNumber(v)
+		if err != nil {
+			if fallback != nil {
+				return fallback(buf, v)
+			}
+			return err
+		}
+		buf.WriteString(s)
+	case String:
+		if fallback != nil && !utf8.ValidString(string(v)) {
+			return fallback(buf, v)
+		}
+		return canonicalString(buf, string(v))
+	case *Array:
+		buf.WriteByte('[')
+		for i := range v.Len() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := canonicalWrite(buf, v.Elem(i).Value, fallback); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case Object:
+		buf.WriteByte('{')
+		for i, k := range v.Keys() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := canonicalWrite(buf, k.Value, fallback); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := canonicalWrite(buf, v.Get(k).Value, fallback); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case Set:
+		if v.Len() == 0 {
+			buf.WriteString("set()")
+			break
+		}
+		buf.WriteByte('{')
+		for i, elem := range v.Slice() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := canonicalWrite(buf, elem.Value, fallback); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		if fallback != nil {
+			return fallback(buf, v)
+		}
+		return &UnsupportedValueError{Value: v}
+	}
+	return nil
+}
+
+// Fingerprint returns a compact string that identifies v, such that values
+// that compare equal under Compare have the same fingerprint, e.g. 1 and 1.0,
+// or sets whose elements were inserted in different orders. It is suitable for
+// cache keys and logging.
+//
+// For values supported by Canonical, the fingerprint is the canonical encoding
+// of v. Other values are written in a deterministic form: variables by name,
+// refs and calls with their elements encoded recursively, non-finite numbers
+// as -Inf, +Inf or NaN, invalid UTF-8 strings quoted like strconv.Quote, and
+// anything else, e.g. comprehensions, with their String method. Only for the
+// latter, equal values might have different fingerprints.
+func Fingerprint(v Value) string {
+	var buf bytes.Buffer
+	// fingerprintFallback handles every value that canonicalWrite can't, so
+	// there is no error to return.
+	_ = canonicalWrite(&buf, v, fingerprintFallback)
+	return buf.String()
+}
+
+func fingerprintFallback(buf *bytes.Buffer, v Value) error {
+	switch v := v.(type) {
+	case Number:
+		rank, _ := nonFiniteRank(v)
+		switch rank {
+		case -1:
+			buf.WriteString("-Inf")
+		case 1:
+			buf.WriteString("+Inf")
+		case 2:
+			buf.WriteString("NaN")
+		default:
+			buf.WriteString(string(v))
+		}
+	case String:
+		buf.WriteString(strconv.Quote(string(v)))
+	case Var:
+		buf.WriteString(string(v))
+	case Ref:
+		for i, t := range v {
+			if i > 0 {
+				buf.WriteByte('[')
+			}
+			if err := canonicalWrite(buf, t.Value, fingerprintFallback); err != nil {
+				return err
+			}
+			if i > 0 {
+				buf.WriteByte(']')
+			}
+		}
+	case Call:
+		if len(v) == 0 {
+			break
+		}
+		if err := canonicalWrite(buf, v[0].Value, fingerprintFallback); err != nil {
+			return err
+		}
+		buf.WriteByte('(')
+		for i, t := range v[1:] {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := canonicalWrite(buf, t.Value, fingerprintFallback); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(')')
+	default:
+		buf.WriteString(v.String())
+	}
+	return nil
+}
+
+// canonicalString writes s as a JSON string, escaping only the characters
+// that must be escaped.
+func canonicalString(buf *bytes.Buffer, s string) error {
+	if !utf8.ValidString(s) {
+		return errors.New("canonical: string is not valid UTF-8: " + strconv.Quote(s))
+	}
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r < 0x20:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hexDigits[r>>4])
+			buf.WriteByte(hexDigits[r&0xf])
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// canonicalMaxPlainExponent is the largest decimal exponent (in absolute
+// value) of numbers that are written without scientific notation.
+const canonicalMaxPlainExponent = 20
+
+var (
+	bigTwo  = big.NewInt(2)
+	bigFive = big.NewInt(5)
+)
+
+// canonicalNumber returns the shortest decimal representation of n. Numbers
+// with equal numeric values have the same representation.
+func canonicalNumber(n Number) (string, error) {
+	if i, ok := n.Int64(); ok {
+		return strconv.FormatInt(i, 10), nil
+	}
+	if _, ok := nonFiniteRank(n); ok {
+		return "", errors.New("canonical: non-finite number " + string(n))
+	}
+
+	// Numbers with huge exponents always use the exponent form, which can be
+	// built from their digits without computing their numeric values.
+	if d, ok := hugeDecimal(n); ok {
+		var sign string
+		if d.sign < 0 {
+			sign = "-"
+		}
+		digits := d.hi + d.lo
+		s := sign + digits[:1]
+		if len(digits) > 1 {
+			s += "." + digits[1:]
+		}
+		return s + "e" + strconv.FormatInt(d.exp-1, 10), nil
+	}
+
+	// Decimal numbers are rationals whose denominator only has the prime
+	// factors 2 and 5, so n = m * 10^-scale for some integer m.
+	r, ok := tryNumberRat(n)
+	if !ok {
+		return "", errors.New("canonical: malformed number " + string(n))
+	}
+	if r.Sign() == 0 {
+		return "0", nil
+	}
+	den := new(big.Int).Set(r.Denom())
+	var twos, fives int
+	var rem big.Int
+	for {
+		q, _ := new(big.Int).QuoRem(den, bigTwo, &rem)
+		if rem.Sign() != 0 {
+			break
+		}
+		den, twos = q, twos+1
+	}
+	for {
+		q, _ := new(big.Int).QuoRem(den, bigFive, &rem)
+		if rem.Sign() != 0 {
+			break
+		}
+		den, fives = q, fives+1
+	}
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return "", errors.New("canonical: number is not a decimal " + string(n))
+	}
+
+	scale := max(twos, fives)
+	m := new(big.Int).Set(r.Num())
+	m.Mul(m, new(big.Int).Exp(bigTwo, big.NewInt(int64(scale-twos)), nil))
+	m.Mul(m, new(big.Int).Exp(bigFive, big.NewInt(int64(scale-fives)), nil))
+
+	digits := m.String()
+	var sign string
+	if digits[0] == '-' {
+		sign, digits = "-", digits[1:]
+	}
+	trimmed := strings.TrimRight(digits, "0")
+	scale -= len(digits) - len(trimmed)
+	digits = trimmed
+
+	// exp is the decimal exponent of the first digit.
+	exp := len(digits) - 1 - scale
+	switch {
+	case exp > canonicalMaxPlainExponent || exp < -canonicalMaxPlainExponent:
+		s := sign + digits[:1]
+		if len(digits) > 1 {
+			s += "." + digits[1:]
+		}
+		return s + "e" + strconv.Itoa(exp), nil
+	case scale <= 0:
+		return sign + digits + strings.Repeat("0", -scale), nil
+	case scale < len(digits):
+		return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:], nil
+	default:
+		return sign + "0." + strings.Repeat("0", scale-len(digits)) + digits, nil
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		note  string
+		input Value
+		exp   string
+	}{
+		{"null", Null{}, `null`},
+		{"boolean", Boolean(true), `true`},
+		{"int", Number("-12"), `-12`},
+		{"int trailing zeros", Number("1.000"), `1`},
+		{"int exponent", Number("1e0"), `1`},
+		{"zero", Number("0.0"), `0`},
+		{"negative zero", Number("-0.0e5"), `0`},
+		{"decimal", Number("1.50"), `1.5`},
+		{"decimal exponent", Number("15e-1"), `1.5`},
+		{"small decimal", Number("-0.00025"), `-0.00025`},
+		{"large int", Number("1234567890123456789012"), `1.234567890123456789012e21`},
+		{"large int exponent 20", Number("123456789012345678901"), `123456789012345678901`},
+		{"large int plain", Number("12345678901234567890"), `12345678901234567890`},
+		{"large exponent", Number("1.0e21"), `1e21`},
+		{"tiny", Number("25e-30"), `2.5e-29`},
+		{"string", String("a\"\\\n\x00é"), `"a\"\\\n\u0000é"`},
+		{"array", MustParseTerm(`[1.0, "a", [null]]`).Value, `[1,"a",[null]]`},
+		{"object", MustParseTerm(`{"b": 2.0, "a": {3: 4}}`).Value, `{"a":{3:4},"b":2}`},
+		{"object number keys", MustParseTerm(`{2.0: "b", 1: "a"}`).Value, `{1:"a",2:"b"}`},
+		{"set", MustParseTerm(`{"b", 10, 2.50}`).Value, `{2.5,10,"b"}`},
+		{"empty", MustParseTerm(`[{}, set()]`).Value, `[{},set()]`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			bs, err := Canonical(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(bs) != tc.exp {
+				t.Fatalf("expected %s but got %s", tc.exp, bs)
+			}
+			if parsed := MustParseTerm(string(bs)); Compare(parsed.Value, tc.input) != 0 {
+				t.Fatalf("expected %s to parse back into %v but got %v", bs, tc.input, parsed)
+			}
+		})
+	}
+}
+
+func TestCanonicalHugeExponent(t *testing.T) {
+	// The parser rejects numbers this large, so they are not parsed back.
+	tests := []struct {
+		input Number
+		exp   string
+	}{
+		{"-120.50e999999", `-1.205e1000001`},
+		{"0.001e-5000000", `1e-5000003`},
+		{"1e999999999", `1e999999999`},
+	}
+	for _, tc := range tests {
+		bs, err := Canonical(tc.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bs) != tc.exp {
+			t.Errorf("expected %s for %v but got %s", tc.exp, tc.input, bs)
+		}
+	}
+}
+
+func TestCanonicalErrors(t *testing.T) {
+	for _, v := range []Value{Var("x"), MustParseRef("data.x"), Number("Inf"), NewArray(NumberTerm("NaN")), String("\xff")} {
+		if _, err := Canonical(v); err == nil {
+			t.Errorf("expected error for %v", v)
+		}
+	}
+
+	var uve *UnsupportedValueError
+	if _, err := Canonical(Var("x")); !errors.As(err, &uve) {
+		t.Fatalf("expected unsupported value error but got %v", err)
+	}
+}
+
+func TestCanonicalRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	seen := map[string]Value{}
+
+	for range 2000 {
+		v := GenRandomValue(rng, 3)
+		bs, err := Canonical(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Values that compare equal must be encoded identically.
+		w := respell(rng, v)
+		bs2, err := Canonical(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bs) != string(bs2) {
+			t.Fatalf("expected %v and %v to be encoded identically but got %s and %s", v, w, bs, bs2)
+		}
+
+		// The encoding must round-trip.
+		if parsed := MustParseTerm(string(bs)); Compare(parsed.Value, v) != 0 {
+			t.Fatalf("expected %s to parse back into %v but got %v", bs, v, parsed)
+		}
+
+		// Values that do not compare equal must not collide.
+		if other, ok := seen[string(bs)]; ok && Compare(other, v) != 0 {
+			t.Fatalf("expected %v and %v to be encoded differently but both got %s", other, v, bs)
+		}
+		seen[string(bs)] = v
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  string
+	}{
+		{`1`, `1.0`, `1`},
+		{`{3, 1, 2}`, `{2.0, 3, 1e0}`, `{1,2,3}`},
+		{`{"b": [1, 2], "a": null}`, `{"a": null, "b": [1.0, 2]}`, `{"a":null,"b":[1,2]}`},
+		{`[x, data.a[y]]`, `[x, data.a[y]]`, `[x,data["a"][y]]`},
+		{`[count({1, 2.0})]`, `[count({2, 1})]`, `[count({1,2})]`},
+	}
+	for _, tc := range tests {
+		a, b := MustParseTerm(tc.a).Value, MustParseTerm(tc.b).Value
+		if fa, fb := Fingerprint(a), Fingerprint(b); fa != tc.exp || fb != tc.exp {
+			t.Errorf("expected fingerprint %s for %v and %v but got %s and %s", tc.exp, a, b, fa, fb)
+		}
+	}
+
+	for _, pair := range [][2]Value{{Number("inf"), Number("+Infinity")}, {Number("NaN"), Number("-nan")}} {
+		if fa, fb := Fingerprint(pair[0]), Fingerprint(pair[1]); fa != fb {
+			t.Errorf("expected %v and %v to have the same fingerprint but got %s and %s", pair[0], pair[1], fa, fb)
+		}
+	}
+	if fa, fb := Fingerprint(String("\xff")), Fingerprint(String(`\xff`)); fa == fb {
+		t.Errorf("expected different fingerprints but both got %s", fa)
+	}
+}
+
+func TestFingerprintRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 2000 {
+		v := GenRandomValue(rng, 3)
+		w := respell(rng, v)
+		if !ValueEqual(v, w) {
+			t.Fatalf("expected %v and %v to be equal", v, w)
+		}
+		fv, fw := Fingerprint(v), Fingerprint(w)
+		if fv != fw {
+			t.Fatalf("expected %v and %v to have the same fingerprint but got %s and %s", v, w, fv, fw)
+		}
+		if len(fv) > len(v.String()) {
+			t.Fatalf("expected fingerprint %s to be no longer than %v", fv, v)
+		}
+	}
+}
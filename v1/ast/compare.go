@@ -5,9 +5,11 @@
 package ast
 
 import (
-	"encoding/json"
+	"cmp"
+	"context"
 	"fmt"
-	"math/big"
+	"reflect"
+	"unsafe"
 )
 
 // Compare returns an integer indicating whether two AST values are less than,
@@ -15,13 +17,24 @@ import (
 //
 // If a is less than b, the return value is negative. If a is greater than b,
 // the return value is positive. If a is equal to b, the return value is zero.
+// Only the sign of the return value is meaningful, not its magnitude, so test
+// for Compare(a, b) < 0 rather than Compare(a, b) == -1, or use Order.
 //
 // Different types are never equal to each other. For comparison purposes, types
 // are sorted as follows:
 //
 // nil < Null < Boolean < Number < String < Var < Ref < Array < Object < Set <
 // ArrayComprehension < ObjectComprehension < SetComprehension < Expr < SomeDecl
-// < With < Body < Rule < Import < Package < Module.
+// < With < Body < Rule < Import < Package < Module. Values defined outside of
+// this package can be compared if they implement OrderedValue, and sort after
+// all of these types.
+//
+// Terms are compared by their values. Both nil terms and terms whose Value is
+// nil, e.g. from partially constructed ASTs, are treated like nil.
+//
+// Numbers are compared by their numeric value, so 1, 1.0 and 1e0 are equal.
+// Non-finite numbers are ordered -Inf < finite < +Inf < NaN, and NaN is equal
+// to NaN.
 //
 // Arrays and Refs are equal if and only if both a and b have the same length
 // and all corresponding elements are equal. If one element is not equal, the
@@ -29,14 +42,202 @@ import (
 // are equal but a and b have different lengths, the shorter is considered less
 // than the other.
 //
+// Calls and Args are ordered by their number of terms first, and then compared
+// like Arrays. Since the operator is the first term of a call, calls with the
+// same arity are ordered by operator first.
+//
 // Objects are considered equal if and only if both a and b have the same sorted
-// (key, value) pairs and are of the same length. Other comparisons are
-// consistent but not defined.
+// (key, value) pairs and are of the same length. Otherwise, the (key, value)
+// pairs are compared in key order, first by key and then by value, and the
+// return value is the same as for the first differing pair. If all pairs are
+// equal, the object with fewer keys is less. CompareObjectsVerbose also
+// reports the key at which two objects differ.
 //
 // Sets are considered equal if and only if the symmetric difference of a and b
-// is empty.
-// Other comparisons are consistent but not defined.
+// is empty. Otherwise, their elements are compared in sorted order like arrays,
+// so {1, 3} is less than {2} but greater than {1}. Use CompareSetByCardinality
+// to order sets by size first.
+//
+// Compare panics if a or b is not a value it knows how to compare. Use
+// CompareErr to get an error instead.
 func Compare(a, b any) int {
+	return compare(a, b)
+}
+
+// Ordering is the result of Order.
+type Ordering int
+
+const (
+	// OrderLess means that a is less than b.
+	OrderLess Ordering = -1
+	// OrderEqual means that a is equal to b.
+	OrderEqual Ordering = 0
+	// OrderGreater means that a is greater than b.
+	OrderGreater Ordering = 1
+)
+
+func (o Ordering) String() string {
+	switch o {
+	case OrderLess:
+		return "less"
+	case OrderEqual:
+		return "equal"
+	case OrderGreater:
+		return "greater"
+	}
+	return fmt.Sprintf("Ordering(%d)", int(o))
+}
+
+// Order compares a and b like Compare, and returns whether a is less than,
+// equal to, or greater than b as an Ordering.
+func Order(a, b any) Ordering {
+	switch c := Compare(a, b); {
+	case c < 0:
+		return OrderLess
+	case c > 0:
+		return OrderGreater
+	}
+	return OrderEqual
+}
+
+// UnsupportedValueError is returned by CompareErr when one of the operands
+// cannot be compared, either because its type is unknown or because it is a
+// malformed Number.
+type UnsupportedValueError struct {
+	Value any
+}
+
+func (e *UnsupportedValueError) Error() string {
+	if n, ok := e.Value.(Number); ok {
+		return fmt.Sprintf("illegal value: %T %q", n, string(n))
+	}
+	return fmt.Sprintf("illegal value: %T", e.Value)
+}
+
+// CompareErr is like Compare but returns an *UnsupportedValueError instead of
+// panicking when it encounters a value that cannot be compared.
+func CompareErr(a, b any) (res int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*UnsupportedValueError)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	c := comparer{errors: true}
+	return c.compare(a, b), nil
+}
+
+// UndefinedOrderError is returned by CompareStrict when the order of two
+// values depends on the order of two unequal objects or sets, A and B, which
+// is consistent but not semantically meaningful.
+type UndefinedOrderError struct {
+	A, B Value
+}
+
+func (e *UndefinedOrderError) Error() string {
+	return fmt.Sprintf("undefined order of unequal %vs: %v and %v", ValueName(e.A), e.A, e.B)
+}
+
+// CompareStrict is like CompareErr but returns an *UndefinedOrderError instead
+// of ordering a and b if the result would be decided by comparing two unequal
+// objects or two unequal sets, either a and b themselves or elements at the
+// same position of arrays, refs or calls. Equal values still compare as 0.
+// Values of different types have a defined order, so an object and a set, or
+// arrays that only differ in length, are ordered as usual.
+func CompareStrict(a, b any) (int, error) {
+	c, err := CompareErr(a, b)
+	if err != nil || c == 0 {
+		return c, err
+	}
+	if x, y := undefinedOrder(a, b); x != nil {
+		return 0, &UndefinedOrderError{A: x, B: y}
+	}
+	return c, nil
+}
+
+// undefinedOrder returns the pair of objects or sets whose order decides the
+// order of the unequal values a and b, if any.
+func undefinedOrder(a, b any) (Value, Value) {
+	if t, ok := a.(*Term); ok && t != nil {
+		a = t.Value
+	}
+	if t, ok := b.(*Term); ok && t != nil {
+		b = t.Value
+	}
+	x, ok1 := a.(Value)
+	y, ok2 := b.(Value)
+	if !ok1 || !ok2 || sortOrder(x) != sortOrder(y) {
+		return nil, nil
+	}
+
+	var xs, ys []*Term
+	switch x := x.(type) {
+	case Object, Set:
+		return x, y
+	case *Array:
+		xs, ys = x.elems, y.(*Array).elems
+	case Ref:
+		xs, ys = x, y.(Ref)
+	case Call:
+		// Calls are ordered by their number of operands first.
+		if len(x) != len(y.(Call)) {
+			return nil, nil
+		}
+		xs, ys = x, y.(Call)
+	}
+	for i := range min(len(xs), len(ys)) {
+		if compare(xs[i], ys[i]) != 0 {
+			return undefinedOrder(xs[i], ys[i])
+		}
+	}
+	return nil, nil
+}
+
+// compare implements Compare.
+func compare(a, b any) int {
+	var c comparer
+	return c.compare(a, b)
+}
+
+// comparer implements Compare. Its fields configure the variants of Compare,
+// e.g. CompareWith and CompareCtx, and its zero value compares exactly like
+// Compare. The Compare methods of AST nodes, e.g. Expr.Compare, use it as
+// well, so that there is a single implementation of the ordering.
+type comparer struct {
+	opts CompareOptions
+
+	// ctx is checked every compareCtxInterval values if not nil.
+	ctx     context.Context
+	visited int
+
+	// maxDepth is the maximum depth of nested values if limitDepth is set,
+	// and depth is the depth of the values currently being compared.
+	limitDepth bool
+	maxDepth   int
+	depth      int
+
+	// entered is set by enter while it calls compare.
+	entered bool
+
+	// errors makes c panic with an *UnsupportedValueError on values it cannot
+	// compare, for callers that recover it and return it as an error.
+	errors bool
+
+	// visiting holds the pairs of native maps of the lazy objects that are
+	// currently being compared. See lazyObjects.
+	visiting map[[2]unsafe.Pointer]struct{}
+}
+
+func (c *comparer) compare(a, b any) int {
+	if c.ctx != nil || c.limitDepth {
+		if !c.entered {
+			return c.enter(a, b)
+		}
+		c.entered = false
+	}
 
 	if t, ok := a.(*Term); ok {
 		if t == nil {
@@ -64,8 +265,29 @@ func Compare(a, b any) int {
 		return 1
 	}
 
-	sortA := sortOrder(a)
-	sortB := sortOrder(b)
+	sortA, ok := trySortOrder(a)
+	if !ok {
+		panic(c.illegalValue(a))
+	}
+	sortB, ok := trySortOrder(b)
+	if !ok {
+		panic(c.illegalValue(b))
+	}
+
+	if compareStatsEnabled.Load() {
+		recordCompareStats(sortA, sortB)
+	}
+
+	if c.opts.NullsLast {
+		_, na := a.(Null)
+		_, nb := b.(Null)
+		if na != nb {
+			if na {
+				return 1
+			}
+			return -1
+		}
+	}
 
 	if sortA < sortB {
 		return -1
@@ -86,63 +308,23 @@ func Compare(a, b any) int {
 		}
 		return 1
 	case Number:
-		if ai, err := json.Number(a).Int64(); err == nil {
-			if bi, err := json.Number(b.(Number)).Int64(); err == nil {
-				if ai == bi {
-					return 0
-				}
-				if ai < bi {
-					return -1
-				}
-				return 1
-			}
+		b := b.(Number)
+		if c.opts.SignedZero && numberIsZero(a) && numberIsZero(b) {
+			return CompareNumberSigned(a, b)
 		}
-
-		// We use big.Rat for comparing big numbers.
-		// It replaces big.Float due to following reason:
-		// big.Float comes with a default precision of 64, and setting a
-		// larger precision results in more memory being allocated
-		// (regardless of the actual number we are parsing with SetString).
-		//
-		// Note: If we're so close to zero that big.Float says we are zero, do
-		// *not* big.Rat).SetString on the original string it'll potentially
-		// take very long.
-		var bigA, bigB *big.Rat
-		fa, ok := new(big.Float).SetString(string(a))
-		if !ok {
-			panic("illegal value")
-		}
-		if fa.IsInt() {
-			if i, _ := fa.Int64(); i == 0 {
-				bigA = new(big.Rat).SetInt64(0)
-			}
+		if c.opts.NumberEpsilon != nil {
+			return CompareNumberApprox(a, b, c.opts.NumberEpsilon)
 		}
-		if bigA == nil {
-			bigA, ok = new(big.Rat).SetString(string(a))
-			if !ok {
-				panic("illegal value")
-			}
-		}
-
-		fb, ok := new(big.Float).SetString(string(b.(Number)))
+		res, ok := tryCompareNumbers(a, b)
 		if !ok {
-			panic("illegal value")
-		}
-		if fb.IsInt() {
-			if i, _ := fb.Int64(); i == 0 {
-				bigB = new(big.Rat).SetInt64(0)
-			}
-		}
-		if bigB == nil {
-			bigB, ok = new(big.Rat).SetString(string(b.(Number)))
-			if !ok {
-				panic("illegal value")
-			}
+			panic(c.illegalValue(malformedNumber(a, b)))
 		}
-
-		return bigA.Cmp(bigB)
+		return res
 	case String:
 		b := b.(String)
+		if c.opts.StringFold {
+			return CompareStringFold(a, b)
+		}
 		if a.Equal(b) {
 			return 0
 		}
@@ -153,146 +335,569 @@ func Compare(a, b any) int {
 	case Var:
 		return VarCompare(a, b.(Var))
 	case Ref:
-		b := b.(Ref)
-		return termSliceCompare(a, b)
+		return c.termSlice(a, b.(Ref))
 	case *Array:
-		b := b.(*Array)
-		return termSliceCompare(a.elems, b.elems)
-	case *lazyObj:
-		return Compare(a.force(), b)
-	case *object:
-		if x, ok := b.(*lazyObj); ok {
-			b = x.force()
-		}
-		b := b.(*object)
-		return a.Compare(b)
+		return c.termSlice(a.elems, b.(*Array).elems)
+	case Object:
+		return c.object(a, b.(Object))
 	case Set:
 		b := b.(Set)
-		return a.Compare(b)
+		if c.opts.SetsByCardinality {
+			if cmp := cmp.Compare(a.Len(), b.Len()); cmp != 0 {
+				return cmp
+			}
+		}
+		return c.termSlice(a.Slice(), b.Slice())
 	case *ArrayComprehension:
 		b := b.(*ArrayComprehension)
-		if cmp := Compare(a.Term, b.Term); cmp != 0 {
+		if c.opts.AlphaEquivalence {
+			a, b = alphaCanonical(a).(*ArrayComprehension), alphaCanonical(b).(*ArrayComprehension)
+		}
+		if cmp := c.compare(a.Term, b.Term); cmp != 0 {
 			return cmp
 		}
-		return a.Body.Compare(b.Body)
+		return c.compare(a.Body, b.Body)
 	case *ObjectComprehension:
 		b := b.(*ObjectComprehension)
-		if cmp := Compare(a.Key, b.Key); cmp != 0 {
+		if c.opts.AlphaEquivalence {
+			a, b = alphaCanonical(a).(*ObjectComprehension), alphaCanonical(b).(*ObjectComprehension)
+		}
+		if cmp := c.compare(a.Key, b.Key); cmp != 0 {
 			return cmp
 		}
-		if cmp := Compare(a.Value, b.Value); cmp != 0 {
+		if cmp := c.compare(a.Value, b.Value); cmp != 0 {
 			return cmp
 		}
-		return a.Body.Compare(b.Body)
+		return c.compare(a.Body, b.Body)
 	case *SetComprehension:
 		b := b.(*SetComprehension)
-		if cmp := Compare(a.Term, b.Term); cmp != 0 {
+		if c.opts.AlphaEquivalence {
+			a, b = alphaCanonical(a).(*SetComprehension), alphaCanonical(b).(*SetComprehension)
+		}
+		if cmp := c.compare(a.Term, b.Term); cmp != 0 {
 			return cmp
 		}
-		return a.Body.Compare(b.Body)
+		return c.compare(a.Body, b.Body)
 	case Call:
 		b := b.(Call)
-		return termSliceCompare(a, b)
+		if cmp := cmp.Compare(len(a), len(b)); cmp != 0 {
+			return cmp
+		}
+		return c.termSlice(a, b)
 	case *Expr:
-		b := b.(*Expr)
-		return a.Compare(b)
+		return c.expr(a, b.(*Expr))
 	case *SomeDecl:
-		b := b.(*SomeDecl)
-		return a.Compare(b)
+		return c.termSlice(a.Symbols, b.(*SomeDecl).Symbols)
 	case *Every:
-		b := b.(*Every)
-		return a.Compare(b)
+		return c.every(a, b.(*Every))
 	case *With:
-		b := b.(*With)
-		return a.Compare(b)
+		return c.with(a, b.(*With))
 	case Body:
-		b := b.(Body)
-		return a.Compare(b)
+		return c.body(a, b.(Body))
 	case *Head:
-		b := b.(*Head)
-		return a.Compare(b)
+		return c.head(a, b.(*Head))
 	case *Rule:
-		b := b.(*Rule)
-		return a.Compare(b)
+		return c.rule(a, b.(*Rule))
 	case Args:
 		b := b.(Args)
-		return termSliceCompare(a, b)
+		if cmp := cmp.Compare(len(a), len(b)); cmp != 0 {
+			return cmp
+		}
+		return c.termSlice(a, b)
 	case *Import:
-		b := b.(*Import)
-		return a.Compare(b)
+		return c.imp(a, b.(*Import))
 	case *Package:
-		b := b.(*Package)
-		return a.Compare(b)
+		return c.termSlice(a.Path, b.(*Package).Path)
 	case *Annotations:
-		b := b.(*Annotations)
-		return a.Compare(b)
+		return a.Compare(b.(*Annotations))
 	case *Module:
-		b := b.(*Module)
-		return a.Compare(b)
+		return c.module(a, b.(*Module))
+	case OrderedValue:
+		return a.CompareValue(b.(OrderedValue))
+	}
+	panic(c.illegalValue(a))
+}
+
+// illegalValue returns the value to panic with when x cannot be compared.
+// Compare has always panicked with a string, so only callers that set
+// c.errors get an *UnsupportedValueError.
+func (c *comparer) illegalValue(x any) any {
+	if c.errors {
+		return &UnsupportedValueError{Value: x}
+	}
+	if _, ok := x.(Number); ok {
+		return "illegal value"
+	}
+	return fmt.Sprintf("illegal value: %T", x)
+}
+
+// enter checks the context and depth limit of c before comparing a and b. It
+// sets c.entered so that c.compare does not check them again for a and b, but
+// only for the values nested in them.
+func (c *comparer) enter(a, b any) int {
+	if c.ctx != nil {
+		c.visited++
+		if c.visited%compareCtxInterval == 0 {
+			if err := c.ctx.Err(); err != nil {
+				panic(compareCanceledError{err: err})
+			}
+		}
+	}
+	if c.limitDepth {
+		if c.depth > c.maxDepth {
+			panic(&MaxDepthError{MaxDepth: c.maxDepth})
+		}
+		c.depth++
+		defer func() { c.depth-- }()
+	}
+	c.entered = true
+	return c.compare(a, b)
+}
+
+func (c *comparer) termSlice(a, b []*Term) int {
+	// Slices that start at the same element share their common prefix, so
+	// only their lengths can differ. This is common when a term is compared
+	// to itself, e.g. during cache lookups.
+	if len(a) > 0 && len(b) > 0 && &a[0] == &b[0] {
+		return cmp.Compare(len(a), len(b))
+	}
+	minLen := min(len(b), len(a))
+	for i := range minLen {
+		if cmp := c.compare(a[i], b[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// object compares a and b without forcing lazy objects: only the values of
+// keys that are actually inspected are converted, so the comparison is cheap
+// if the objects differ early on.
+func (c *comparer) object(a, b Object) int {
+	if x, ok := a.(*lazyObj); ok && x.strict != nil {
+		a = x.strict
+	}
+	if x, ok := b.(*lazyObj); ok && x.strict != nil {
+		b = x.strict
+	}
+	switch x := a.(type) {
+	case *object:
+		if y, ok := b.(*object); ok {
+			return c.objects(x, y)
+		}
+	case *lazyObj:
+		if y, ok := b.(*lazyObj); ok {
+			return c.lazyObjects(x, y)
+		}
+	}
+	akeys := a.Keys()
+	bkeys := b.Keys()
+	minLen := min(len(akeys), len(bkeys))
+	for i := range minLen {
+		if cmp := c.compare(akeys[i], bkeys[i]); cmp != 0 {
+			return cmp
+		}
+		if cmp := c.compare(a.Get(akeys[i]), b.Get(bkeys[i])); cmp != 0 {
+			return cmp
+		}
+	}
+	return cmp.Compare(len(akeys), len(bkeys))
+}
+
+func (c *comparer) objects(a, b *object) int {
+	// Ensure that keys are in canonical sorted order before use!
+	akeys := a.sortedKeys()
+	bkeys := b.sortedKeys()
+	minLen := min(len(akeys), len(bkeys))
+	for i := range minLen {
+		if cmp := c.compare(akeys[i].key, bkeys[i].key); cmp != 0 {
+			return cmp
+		}
+		if cmp := c.compare(akeys[i].value, bkeys[i].value); cmp != 0 {
+			return cmp
+		}
 	}
-	panic(fmt.Sprintf("illegal value: %T", a))
+	return cmp.Compare(len(akeys), len(bkeys))
+}
+
+// lazyObjects compares two lazy objects without forcing them. The native map
+// of a lazy object may contain itself, either as a map or as the lazy object
+// wrapping it, so the pairs of native maps that are currently being compared
+// are kept in c.visiting. A pair that is visited again is part of a cycle on
+// both sides and cannot produce a difference that is not also found
+// elsewhere, so it is considered equal.
+func (c *comparer) lazyObjects(a, b *lazyObj) int {
+	pair := [2]unsafe.Pointer{reflect.ValueOf(a.native).UnsafePointer(), reflect.ValueOf(b.native).UnsafePointer()}
+	if pair[0] == pair[1] {
+		return 0
+	}
+	if _, ok := c.visiting[pair]; ok {
+		return 0
+	}
+	if c.visiting == nil {
+		c.visiting = map[[2]unsafe.Pointer]struct{}{}
+	}
+	c.visiting[pair] = struct{}{}
+	defer delete(c.visiting, pair)
+
+	akeys := a.Keys()
+	bkeys := b.Keys()
+	for i := range min(len(akeys), len(bkeys)) {
+		if cmp := c.compare(akeys[i], bkeys[i]); cmp != 0 {
+			return cmp
+		}
+		if cmp := c.compare(a.Get(akeys[i]), b.Get(bkeys[i])); cmp != 0 {
+			return cmp
+		}
+	}
+	return cmp.Compare(len(akeys), len(bkeys))
+}
+
+// expr implements Expr.Compare.
+func (c *comparer) expr(a, b *Expr) int {
+	if a == nil {
+		if b == nil {
+			return 0
+		}
+		return -1
+	} else if b == nil {
+		return 1
+	}
+
+	if cmp := cmp.Compare(a.sortOrder(), b.sortOrder()); cmp != 0 {
+		return cmp
+	}
+	if cmp := cmp.Compare(a.Index, b.Index); cmp != 0 {
+		return cmp
+	}
+	if a.Negated != b.Negated {
+		if a.Negated {
+			return 1
+		}
+		return -1
+	}
+
+	switch t := a.Terms.(type) {
+	case *Term:
+		if cmp := c.compare(t, b.Terms.(*Term)); cmp != 0 {
+			return cmp
+		}
+	case []*Term:
+		if cmp := c.termSlice(t, b.Terms.([]*Term)); cmp != 0 {
+			return cmp
+		}
+	case *SomeDecl:
+		if cmp := c.compare(t, b.Terms.(*SomeDecl)); cmp != 0 {
+			return cmp
+		}
+	case *Every:
+		if cmp := c.compare(t, b.Terms.(*Every)); cmp != 0 {
+			return cmp
+		}
+	}
+
+	return c.withs(a.With, b.With)
+}
+
+// every implements Every.Compare.
+func (c *comparer) every(a, b *Every) int {
+	if cmp := c.compare(a.Key, b.Key); cmp != 0 {
+		return cmp
+	}
+	if cmp := c.compare(a.Value, b.Value); cmp != 0 {
+		return cmp
+	}
+	if cmp := c.compare(a.Domain, b.Domain); cmp != 0 {
+		return cmp
+	}
+	return c.compare(a.Body, b.Body)
+}
+
+// with implements With.Compare.
+func (c *comparer) with(a, b *With) int {
+	if a == nil {
+		if b == nil {
+			return 0
+		}
+		return -1
+	} else if b == nil {
+		return 1
+	}
+	if cmp := c.compare(a.Target, b.Target); cmp != 0 {
+		return cmp
+	}
+	return c.compare(a.Value, b.Value)
+}
+
+func (c *comparer) withs(a, b []*With) int {
+	minLen := min(len(b), len(a))
+	for i := range minLen {
+		if cmp := c.with(a[i], b[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// body implements Body.Compare.
+func (c *comparer) body(a, b Body) int {
+	minLen := min(len(b), len(a))
+	for i := range minLen {
+		if cmp := c.expr(a[i], b[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// head implements Head.Compare.
+func (c *comparer) head(a, b *Head) int {
+	if a == nil {
+		if b == nil {
+			return 0
+		}
+		return -1
+	} else if b == nil {
+		return 1
+	}
+	if a.Assign != b.Assign {
+		if a.Assign {
+			return -1
+		}
+		return 1
+	}
+	if cmp := c.compare(a.Args, b.Args); cmp != 0 {
+		return cmp
+	}
+	if cmp := c.compare(a.Reference, b.Reference); cmp != 0 {
+		return cmp
+	}
+	if cmp := VarCompare(a.Name, b.Name); cmp != 0 {
+		return cmp
+	}
+	if cmp := c.compare(a.Key, b.Key); cmp != 0 {
+		return cmp
+	}
+	return c.compare(a.Value, b.Value)
+}
+
+// rule implements Rule.Compare.
+func (c *comparer) rule(a, b *Rule) int {
+	if a == nil {
+		if b == nil {
+			return 0
+		}
+		return -1
+	} else if b == nil {
+		return 1
+	}
+	if cmp := c.head(a.Head, b.Head); cmp != 0 {
+		return cmp
+	}
+	if a.Default != b.Default {
+		if !a.Default {
+			return -1
+		}
+		return 1
+	}
+	if cmp := c.compare(a.Body, b.Body); cmp != 0 {
+		return cmp
+	}
+	if !c.opts.IgnoreAnnotations {
+		if cmp := annotationsCompare(a.Annotations, b.Annotations); cmp != 0 {
+			return cmp
+		}
+	}
+	return c.rule(a.Else, b.Else)
+}
+
+// imp implements Import.Compare.
+func (c *comparer) imp(a, b *Import) int {
+	if a == nil {
+		if b == nil {
+			return 0
+		}
+		return -1
+	} else if b == nil {
+		return 1
+	}
+	if cmp := c.compare(a.Path, b.Path); cmp != 0 {
+		return cmp
+	}
+	return VarCompare(a.Alias, b.Alias)
+}
+
+// module implements Module.Compare.
+func (c *comparer) module(a, b *Module) int {
+	if a == nil {
+		if b == nil {
+			return 0
+		}
+		return -1
+	} else if b == nil {
+		return 1
+	}
+	if cmp := c.compare(a.Package, b.Package); cmp != 0 {
+		return cmp
+	}
+	minLen := min(len(b.Imports), len(a.Imports))
+	for i := range minLen {
+		if cmp := c.imp(a.Imports[i], b.Imports[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	if cmp := cmp.Compare(len(a.Imports), len(b.Imports)); cmp != 0 {
+		return cmp
+	}
+	if !c.opts.IgnoreAnnotations {
+		if cmp := annotationsCompare(a.Annotations, b.Annotations); cmp != 0 {
+			return cmp
+		}
+	}
+	minLen = min(len(b.Rules), len(a.Rules))
+	for i := range minLen {
+		if cmp := c.rule(a.Rules[i], b.Rules[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return cmp.Compare(len(a.Rules), len(b.Rules))
 }
 
 type termSlice []*Term
 
-func (s termSlice) Less(i, j int) bool { return Compare(s[i].Value, s[j].Value) < 0 }
+func (s termSlice) Less(i, j int) bool { return Compare(s[i], s[j]) < 0 }
 func (s termSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s termSlice) Len() int           { return len(s) }
 
+// Type ranks returned by TypeOrder. Compare orders values of different types
+// by these ranks.
+const (
+	TypeOrderNull                = 0
+	TypeOrderBoolean             = 1
+	TypeOrderNumber              = 2
+	TypeOrderString              = 3
+	TypeOrderVar                 = 4
+	TypeOrderRef                 = 5
+	TypeOrderArray               = 6
+	TypeOrderObject              = 7
+	TypeOrderSet                 = 8
+	TypeOrderArrayComprehension  = 9
+	TypeOrderObjectComprehension = 10
+	TypeOrderSetComprehension    = 11
+	TypeOrderCall                = 12
+	TypeOrderArgs                = 13
+	TypeOrderExpr                = 100
+	TypeOrderSomeDecl            = 101
+	TypeOrderEvery               = 102
+	TypeOrderWith                = 110
+	TypeOrderHead                = 120
+	TypeOrderBody                = 200
+	TypeOrderRule                = 1000
+	TypeOrderImport              = 1001
+	TypeOrderPackage             = 1002
+	TypeOrderAnnotations         = 1003
+	TypeOrderModule              = 10000
+
+	// TypeOrderCustom is the lowest rank of values implementing OrderedValue.
+	// The ranks from TypeOrderCustom upwards are reserved for them, so they
+	// sort after all types defined in this package.
+	TypeOrderCustom = 100000
+)
+
+// OrderedValue can be implemented by Values defined outside of this package
+// so that Compare can order them. Without it, Compare panics when it
+// encounters such values.
+//
+// SortOrder returns the rank of the value's type relative to other custom
+// types. It must not be negative, and the rank used by Compare is
+// TypeOrderCustom plus SortOrder. CompareValue is only called with values of
+// the same rank, which may include values of other custom types that return
+// the same SortOrder, and must return a negative, zero or positive result like
+// Compare.
+type OrderedValue interface {
+	Value
+	SortOrder() int
+	CompareValue(other Value) int
+}
+
+// TypeOrder returns the rank of x's type in the ordering used by Compare: if
+// TypeOrder(a) < TypeOrder(b), then Compare(a, b) < 0. Values of the same type
+// have the same rank. TypeOrder panics with an *UnsupportedValueError if x is
+// not a type that Compare supports.
+func TypeOrder(x any) int {
+	if o, ok := trySortOrder(x); ok {
+		return o
+	}
+	panic(&UnsupportedValueError{Value: x})
+}
+
 func sortOrder(x any) int {
-	switch x.(type) {
+	if o, ok := trySortOrder(x); ok {
+		return o
+	}
+	panic(fmt.Sprintf("illegal value: %T", x))
+}
+
+// trySortOrder is like sortOrder, but returns false if x is not a type that
+// Compare supports.
+func trySortOrder(x any) (int, bool) {
+	switch v := x.(type) {
 	case Null:
-		return 0
+		return TypeOrderNull, true
 	case Boolean:
-		return 1
+		return TypeOrderBoolean, true
 	case Number:
-		return 2
+		return TypeOrderNumber, true
 	case String:
-		return 3
+		return TypeOrderString, true
 	case Var:
-		return 4
+		return TypeOrderVar, true
 	case Ref:
-		return 5
+		return TypeOrderRef, true
 	case *Array:
-		return 6
+		return TypeOrderArray, true
 	case Object:
-		return 7
+		return TypeOrderObject, true
 	case Set:
-		return 8
+		return TypeOrderSet, true
 	case *ArrayComprehension:
-		return 9
+		return TypeOrderArrayComprehension, true
 	case *ObjectComprehension:
-		return 10
+		return TypeOrderObjectComprehension, true
 	case *SetComprehension:
-		return 11
+		return TypeOrderSetComprehension, true
 	case Call:
-		return 12
+		return TypeOrderCall, true
 	case Args:
-		return 13
+		return TypeOrderArgs, true
 	case *Expr:
-		return 100
+		return TypeOrderExpr, true
 	case *SomeDecl:
-		return 101
+		return TypeOrderSomeDecl, true
 	case *Every:
-		return 102
+		return TypeOrderEvery, true
 	case *With:
-		return 110
+		return TypeOrderWith, true
 	case *Head:
-		return 120
+		return TypeOrderHead, true
 	case Body:
-		return 200
+		return TypeOrderBody, true
 	case *Rule:
-		return 1000
+		return TypeOrderRule, true
 	case *Import:
-		return 1001
+		return TypeOrderImport, true
 	case *Package:
-		return 1002
+		return TypeOrderPackage, true
 	case *Annotations:
-		return 1003
+		return TypeOrderAnnotations, true
 	case *Module:
-		return 10000
+		return TypeOrderModule, true
+	case OrderedValue:
+		if r := v.SortOrder(); r >= 0 {
+			return TypeOrderCustom + r, true
+		}
 	}
-	panic(fmt.Sprintf("illegal value: %T", x))
+	return 0, false
 }
 
 func importsCompare(a, b []*Import) int {
@@ -327,50 +932,14 @@ func annotationsCompare(a, b []*Annotations) int {
 	return 0
 }
 
-func rulesCompare(a, b []*Rule) int {
-	minLen := min(len(b), len(a))
-	for i := range minLen {
-		if cmp := a[i].Compare(b[i]); cmp != 0 {
-			return cmp
-		}
-	}
-	if len(a) < len(b) {
-		return -1
-	}
-	if len(b) < len(a) {
-		return 1
-	}
-	return 0
-}
-
 func termSliceCompare(a, b []*Term) int {
-	minLen := min(len(b), len(a))
-	for i := range minLen {
-		if cmp := Compare(a[i], b[i]); cmp != 0 {
-			return cmp
-		}
-	}
-	if len(a) < len(b) {
-		return -1
-	} else if len(b) < len(a) {
-		return 1
-	}
-	return 0
+	var c comparer
+	return c.termSlice(a, b)
 }
 
 func withSliceCompare(a, b []*With) int {
-	minLen := min(len(b), len(a))
-	for i := range minLen {
-		if cmp := Compare(a[i], b[i]); cmp != 0 {
-			return cmp
-		}
-	}
-	if len(a) < len(b) {
-		return -1
-	} else if len(b) < len(a) {
-		return 1
-	}
-	return 0
+	var c comparer
+	return c.withs(a, b)
 }
 
 func VarCompare(a, b Var) int {
@@ -387,19 +956,48 @@ func TermValueCompare(a, b *Term) int {
 	return a.Value.Compare(b.Value)
 }
 
+// TermCompareFunc returns a three-way comparison function over terms that is
+// suitable for use with slices.SortFunc and friends. It orders terms like
+// Compare.
+func TermCompareFunc() func(a, b *Term) int {
+	return termCompare
+}
+
+// ValueCompareFunc returns a three-way comparison function over values that is
+// suitable for use with slices.SortFunc and friends. It orders values like
+// Compare.
+func ValueCompareFunc() func(a, b Value) int {
+	return valueCompare
+}
+
+func termCompare(a, b *Term) int {
+	return Compare(a, b)
+}
+
+func valueCompare(a, b Value) int {
+	return Compare(a, b)
+}
+
+// ValueLess returns true if a is less than b according to Compare.
+func ValueLess(a, b Value) bool {
+	return Compare(a, b) < 0
+}
+
+// ValueGreater returns true if a is greater than b according to Compare.
+func ValueGreater(a, b Value) bool {
+	return Compare(a, b) > 0
+}
+
 func TermValueEqual(a, b *Term) bool {
 	return ValueEqual(a.Value, b.Value)
 }
 
 func ValueEqual(a, b Value) bool {
-	// TODO(ae): why doesn't this work the same?
-	//
-	// case interface{ Equal(Value) bool }:
-	// 	   return v.Equal(b)
-	//
-	// When put on top, golangci-lint even flags the other cases as unreachable..
-	// but TestTopdownVirtualCache will have failing test cases when we replace
-	// the other cases with the above one.. 🤔
+	// NOTE: This deliberately switches over the concrete types rather than
+	// dispatching on interface{ Equal(Value) bool }. Types outside this
+	// package may embed one of the values below (e.g. topdown's vcKeyScope
+	// embeds Ref) and would then get a promoted Equal method that disagrees
+	// with their own Compare.
 	switch v := a.(type) {
 	case Null:
 		return v.Equal(b)
@@ -415,11 +1013,48 @@ func ValueEqual(a, b Value) bool {
 		return v.Equal(b)
 	case *Array:
 		return v.Equal(b)
+	case *object:
+		return v.Equal(b)
+	case *set:
+		return v.Equal(b)
+	case *ArrayComprehension:
+		return v.Equal(b)
+	case *ObjectComprehension:
+		return v.Equal(b)
+	case *SetComprehension:
+		return v.Equal(b)
+	case Call:
+		return v.Equal(b)
 	}
 
 	return a.Compare(b) == 0
 }
 
+// CompareTermSlice compares a and b element by element like Compare. If one
+// slice is a prefix of the other, the shorter slice is less.
+func CompareTermSlice(a, b []*Term) int {
+	return termSliceCompare(a, b)
+}
+
+// EqualTermSlice returns true if a and b have the same length and equal
+// elements. Unlike CompareTermSlice, it does not compute an ordering, so it
+// returns as soon as it finds a pair of elements that are not equal.
+func EqualTermSlice(a, b []*Term) bool {
+	return termSliceEqual(a, b)
+}
+
+// CompareValues compares a and b element by element like Compare, e.g. to
+// order tuples of values without wrapping them in arrays. If one slice is a
+// prefix of the other, the shorter slice is less. Nil values sort first.
+func CompareValues(a, b []Value) int {
+	for i := range min(len(a), len(b)) {
+		if c := compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
 func RefCompare(a, b Ref) int {
 	return termSliceCompare(a, b)
 }
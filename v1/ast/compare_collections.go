@@ -0,0 +1,120 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "cmp"
+
+// CompareSetByCardinality compares a and b by their number of elements first,
+// so smaller sets are always less than larger ones. Sets of the same size are
+// compared like Compare, i.e. by their elements in sorted order. This is a
+// total order that is consistent with Compare on equality.
+func CompareSetByCardinality(a, b Set) int {
+	if c := cmp.Compare(a.Len(), b.Len()); c != 0 {
+		return c
+	}
+	return termSliceCompare(a.Slice(), b.Slice())
+}
+
+// CompareAsSet compares a and b as if they were sets of their elements, like
+// Compare on sets: the order of elements does not matter, and duplicate
+// elements are collapsed, so [1, 2, 1] equals [2, 1]. Elements that are equal
+// according to Compare, such as 1 and 1.0, count as duplicates.
+func CompareAsSet(a, b *Array) int {
+	return NewSet(a.elems...).Compare(NewSet(b.elems...))
+}
+
+// CompareObjectsVerbose compares a and b like Compare and also returns the
+// key at which they diverge, or nil if they are equal. Like Compare, it walks
+// the keys of both objects in sorted order, comparing each pair of keys and
+// then their values:
+//
+//   - If the keys at some position differ, the diverging key is the lesser of
+//     the two, which is present in only one of a and b.
+//   - If the keys are equal but their values differ, the diverging key is that
+//     key, and the result is the result of comparing the values.
+//   - If one object runs out of keys first, the diverging key is the first key
+//     of the other object that it lacks, and the object with fewer keys is
+//     less.
+func CompareObjectsVerbose(a, b Object) (res int, divergingKey *Term) {
+	akeys, bkeys := a.Keys(), b.Keys()
+	for i := range min(len(akeys), len(bkeys)) {
+		if c := Compare(akeys[i], bkeys[i]); c < 0 {
+			return c, akeys[i]
+		} else if c > 0 {
+			return c, bkeys[i]
+		}
+		if c := Compare(a.Get(akeys[i]), b.Get(bkeys[i])); c != 0 {
+			return c, akeys[i]
+		}
+	}
+	switch {
+	case len(akeys) < len(bkeys):
+		return -1, bkeys[len(akeys)]
+	case len(akeys) > len(bkeys):
+		return 1, akeys[len(bkeys)]
+	}
+	return 0, nil
+}
+
+// EqualLazy returns true if a and b contain the same keys and values, like
+// ValueEqual. It is meant for comparing lazy objects, as returned by
+// LazyObject, with each other or with other objects: lazy objects are never
+// forced, and the keys of both objects are compared before any of their
+// values, so no value is converted if the keys differ. Values are then
+// converted one key at a time, stopping at the first one that differs, and
+// nested objects are compared the same way.
+func EqualLazy(a, b Object) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	keys := objectKeysUnsorted(a)
+	for _, k := range keys {
+		if !objectHasKey(b, k) {
+			return false
+		}
+	}
+	for _, k := range keys {
+		x, y := a.Get(k).Value, b.Get(k).Value
+		if ox, ok := x.(Object); ok {
+			if oy, ok := y.(Object); ok {
+				if !EqualLazy(ox, oy) {
+					return false
+				}
+				continue
+			}
+		}
+		if !ValueEqual(x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+// objectKeysUnsorted returns the keys of obj. Unlike Keys, it doesn't sort the
+// keys of lazy objects.
+func objectKeysUnsorted(obj Object) []*Term {
+	if l, ok := obj.(*lazyObj); ok && l.strict == nil {
+		keys := make([]*Term, 0, len(l.native))
+		for k := range l.native {
+			keys = append(keys, StringTerm(k))
+		}
+		return keys
+	}
+	return obj.Keys()
+}
+
+// objectHasKey returns true if obj contains k. Unlike Get, it doesn't convert
+// the value of k if obj is a lazy object.
+func objectHasKey(obj Object, k *Term) bool {
+	if l, ok := obj.(*lazyObj); ok && l.strict == nil {
+		s, ok := k.Value.(String)
+		if !ok {
+			return false
+		}
+		_, ok = l.native[string(s)]
+		return ok
+	}
+	return obj.Get(k) != nil
+}
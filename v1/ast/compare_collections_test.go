@@ -0,0 +1,184 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestEqualLazy(t *testing.T) {
+	tests := []struct {
+		a, b map[string]any
+		exp  bool
+	}{
+		{map[string]any{}, map[string]any{}, true},
+		{map[string]any{"a": 1, "b": map[string]any{"c": "d"}}, map[string]any{"b": map[string]any{"c": "d"}, "a": 1.0}, true},
+		{map[string]any{"a": 1, "b": map[string]any{"c": "d"}}, map[string]any{"a": 1, "b": map[string]any{"c": "e"}}, false},
+		{map[string]any{"a": 1, "b": 2}, map[string]any{"a": 1, "c": 2}, false},
+		{map[string]any{"a": 1}, map[string]any{"a": 1, "b": 2}, false},
+		{map[string]any{"a": []any{1, "x"}}, map[string]any{"a": []any{1, "y"}}, false},
+	}
+
+	for _, tc := range tests {
+		for _, pair := range [][2]Object{
+			{LazyObject(tc.a), LazyObject(tc.b)},
+			{LazyObject(tc.a), MustInterfaceToValue(tc.b).(Object)},
+			{MustInterfaceToValue(tc.a).(Object), LazyObject(tc.b)},
+			{MustInterfaceToValue(tc.a).(Object), MustInterfaceToValue(tc.b).(Object)},
+		} {
+			if act := EqualLazy(pair[0], pair[1]); act != tc.exp {
+				t.Errorf("Expected EqualLazy(%v, %v) to be %v", pair[0], pair[1], tc.exp)
+			}
+			if act := ValueEqual(pair[0], pair[1]); act != tc.exp {
+				t.Errorf("Expected ValueEqual(%v, %v) to be %v", pair[0], pair[1], tc.exp)
+			}
+		}
+	}
+}
+
+func TestEqualLazyDoesNotConvertOnKeyMismatch(t *testing.T) {
+	a := LazyObject(map[string]any{"a": map[string]any{"x": 1}, "b": 2}).(*lazyObj)
+	b := NewObject(Item(StringTerm("a"), ObjectTerm(Item(StringTerm("x"), IntNumberTerm(1)))), Item(StringTerm("c"), IntNumberTerm(2)))
+
+	if EqualLazy(a, b) || EqualLazy(b, a) {
+		t.Fatal("Expected objects to differ")
+	}
+	if a.strict != nil || len(a.cache) != 0 {
+		t.Fatal("Expected no values of the lazy object to be converted")
+	}
+}
+
+func BenchmarkEqualLazy(b *testing.B) {
+	native := func(first int) map[string]any {
+		m := make(map[string]any, 10000)
+		for i := range 10000 {
+			m["key"+strconv.Itoa(i)] = map[string]any{"value": i}
+		}
+		m["key0"] = map[string]any{"value": first}
+		return m
+	}
+	materialized := MustInterfaceToValue(native(-1)).(Object)
+	m := native(0)
+
+	b.Run("EqualLazy", func(b *testing.B) {
+		for range b.N {
+			if EqualLazy(materialized, LazyObject(m)) {
+				b.Fatal("expected objects to differ")
+			}
+		}
+	})
+	b.Run("Compare", func(b *testing.B) {
+		for range b.N {
+			if Compare(materialized, LazyObject(m)) == 0 {
+				b.Fatal("expected objects to differ")
+			}
+		}
+	})
+}
+
+func TestCompareObjectsVerbose(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  int
+		key  string
+	}{
+		{`{}`, `{}`, 0, ``},
+		{`{"a": 1, "b": 2}`, `{"b": 2.0, "a": 1}`, 0, ``},
+		{`{"a": 1}`, `{"b": 1}`, -1, `"a"`},
+		{`{"a": 1, "c": 1}`, `{"a": 1, "b": 1}`, 1, `"b"`},
+		{`{"a": 1}`, `{"a": 1, "b": 0}`, -1, `"b"`},
+		{`{"a": 1, "b": 0}`, `{"a": 1}`, 1, `"b"`},
+		{`{"a": 1, "b": 2}`, `{"a": 1, "b": 3}`, -1, `"b"`},
+		{`{"a": 2, "b": 1}`, `{"a": 1, "b": 3}`, 1, `"a"`},
+		// Values are compared before later keys, like Compare.
+		{`{"a": 2}`, `{"a": 1, "b": 1}`, 1, `"a"`},
+		{`{"a": 1, "b": 2}`, `{"a": 2, "c": 1}`, -1, `"a"`},
+		{`{1: "x"}`, `{"1": "x"}`, -1, `1`},
+	}
+
+	for _, tc := range tests {
+		a := MustParseTerm(tc.a).Value.(Object)
+		b := MustParseTerm(tc.b).Value.(Object)
+		result, key := CompareObjectsVerbose(a, b)
+		if result != tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", tc.exp, a, b, result)
+		}
+		if tc.key == "" && key != nil || tc.key != "" && !key.Equal(MustParseTerm(tc.key)) {
+			t.Errorf("expected diverging key %q for %v and %v but got %v", tc.key, a, b, key)
+		}
+		if sign := Compare(a, b); result != sign {
+			t.Errorf("expected %d to agree with Compare for %v and %v but got %d", sign, a, b, result)
+		}
+		if r, _ := CompareObjectsVerbose(b, a); r != -result {
+			t.Errorf("expected antisymmetric result for %v and %v", a, b)
+		}
+	}
+
+	lazy := LazyObject(map[string]any{"a": 1, "b": 3})
+	if result, key := CompareObjectsVerbose(MustParseTerm(`{"a": 1, "b": 2}`).Value.(Object), lazy); result != -1 || !key.Equal(StringTerm("b")) {
+		t.Fatalf("expected -1 and \"b\" but got %d and %v", result, key)
+	}
+}
+
+func TestCompareSetByCardinality(t *testing.T) {
+	sets := []string{`{1, 3}`, `{2}`, `set()`, `{1}`, `{"a", 1, 2}`, `{1, 2}`, `{1.0, 3.0}`}
+	exp := []string{`set()`, `{1}`, `{2}`, `{1, 2}`, `{1, 3}`, `{1.0, 3.0}`, `{1, 2, "a"}`}
+
+	for range 10 {
+		terms := make([]*Term, len(sets))
+		for i, s := range sets {
+			terms[i] = MustParseTerm(s)
+		}
+		rand.Shuffle(len(terms), func(i, j int) { terms[i], terms[j] = terms[j], terms[i] })
+		slices.SortStableFunc(terms, func(a, b *Term) int {
+			return CompareSetByCardinality(a.Value.(Set), b.Value.(Set))
+		})
+		for i := range exp {
+			if Compare(terms[i], MustParseTerm(exp[i])) != 0 {
+				t.Fatalf("expected %v but got %v", exp, terms)
+			}
+		}
+	}
+
+	// Unlike Compare, smaller sets are less.
+	a, b := MustParseTerm(`{1, 3}`).Value.(Set), MustParseTerm(`{2}`).Value.(Set)
+	if Compare(a, b) >= 0 || CompareSetByCardinality(a, b) <= 0 {
+		t.Fatal("expected orderings to differ")
+	}
+}
+
+func TestCompareAsSet(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  int
+	}{
+		{`[]`, `[]`, 0},
+		{`[1, 2, 3]`, `[3, 1, 2]`, 0},
+		{`[1, 2, 1]`, `[2, 1]`, 0},
+		{`[1, 1, 1]`, `[1]`, 0},
+		{`[1, 1.0]`, `[1]`, 0},
+		{`[[1, 2], {"a": 1}]`, `[{"a": 1}, [1, 2], [1, 2]]`, 0},
+		{`[1, 2]`, `[1, 3]`, -1},
+		{`[3, 1, 1]`, `[2, 1]`, 1},
+		{`[1, 1]`, `[1, 2]`, -1},
+		{`[]`, `[null]`, -1},
+	}
+	for _, tc := range tests {
+		a, b := MustParseTerm(tc.a).Value.(*Array), MustParseTerm(tc.b).Value.(*Array)
+		if act := CompareAsSet(a, b); act != tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", tc.exp, a, b, act)
+		}
+		if act := CompareAsSet(b, a); act != -tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", -tc.exp, b, a, act)
+		}
+		setA, setB := NewSet(a.elems...), NewSet(b.elems...)
+		if exp := Compare(setA, setB); CompareAsSet(a, b) != exp {
+			t.Errorf("expected %v and %v to compare like %v and %v", a, b, setA, setB)
+		}
+	}
+}
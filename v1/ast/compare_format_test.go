@@ -0,0 +1,98 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/format"
+)
+
+func TestCompareModulesSemanticFormatted(t *testing.T) {
+	tests := []struct {
+		note string
+		src  string
+	}{
+		{
+			note: "whitespace",
+			src: `package   test
+import   data.foo
+allow if { input.x == 1 ; input.y == 2 }`,
+		},
+		{
+			note: "rule head assignment",
+			src: `package test
+default allow = false
+q[x] = 1 if { x := "a" }
+f(x) = y if { y := x + 1 } else = 2 if { true }`,
+		},
+		{
+			note: "annotations",
+			src: `package test
+
+# METADATA
+# title: P
+# description: some rule
+p   contains   x if {  some x in [1,2]
+x > 0 }`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			opts := ast.ParserOptions{ProcessAnnotation: true}
+			a := ast.MustParseModuleWithOpts(tc.src, opts)
+			bs, err := format.Ast(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := ast.MustParseModuleWithOpts(string(bs), opts)
+
+			if cmp := ast.CompareModulesSemantic(a, b); cmp != 0 {
+				t.Fatalf("Expected modules to compare equal but got %d:\n\n%v\n\n%v", cmp, a, b)
+			}
+		})
+	}
+}
+
+func TestCompareModulesSemantic(t *testing.T) {
+	opts := ast.ParserOptions{ProcessAnnotation: true}
+	a := ast.MustParseModuleWithOpts(`package test
+
+# METADATA
+# scope: document
+# title: doc
+p := 1
+
+# METADATA
+# title: rule
+p := 1`, opts)
+	b := a.Copy()
+	b.Annotations[0], b.Annotations[1] = b.Annotations[1], b.Annotations[0]
+
+	if ast.Compare(a, b) == 0 {
+		t.Fatal("Expected modules with reordered annotations to differ under Compare")
+	}
+	if cmp := ast.CompareModulesSemantic(a, b); cmp != 0 {
+		t.Fatalf("Expected modules with reordered annotations to compare equal but got %d", cmp)
+	}
+
+	for _, src := range []string{
+		`package other`,
+		`package test
+import input.x`,
+		`package test
+p := 2`,
+		`package test
+p := 1
+q := 1`,
+	} {
+		c := ast.MustParseModuleWithOpts(src, opts)
+		if ast.CompareModulesSemantic(a, c) == 0 {
+			t.Errorf("Expected module to differ from:\n\n%v", c)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"cmp"
+	"math"
+	"strings"
+)
+
+// CompareJSON compares the native Go values a and b, such as the result of
+// decoding JSON, with the ordering of Compare. Both are converted with
+// InterfaceToValue first, so numbers compare by numeric value regardless of
+// their Go type, e.g. float64(1) equals json.Number("1"). An error is returned
+// if a or b cannot be converted.
+func CompareJSON(a, b any) (int, error) {
+	x, err := InterfaceToValue(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := InterfaceToValue(b)
+	if err != nil {
+		return 0, err
+	}
+	return CompareErr(x, y)
+}
+
+// CompareTermGo compares t with the value that InterfaceToValue converts goVal
+// to, with the ordering of Compare. Values of type nil, bool, string, int,
+// int64 and float64 are compared without converting them, and without
+// allocating unless t is a number that cannot be compared as an int64, e.g.
+// 1.5 or 1e3. Any other values are converted first, and an error is returned
+// if that fails.
+func CompareTermGo(t *Term, goVal any) (int, error) {
+	if t == nil || t.Value == nil {
+		return compareTermGoSlow(t, goVal)
+	}
+
+	var order int
+	switch goVal.(type) {
+	case nil:
+		order = TypeOrderNull
+	case bool:
+		order = TypeOrderBoolean
+	case int, int64, float64:
+		order = TypeOrderNumber
+	case string:
+		order = TypeOrderString
+	default:
+		return compareTermGoSlow(t, goVal)
+	}
+	if o := sortOrder(t.Value); o != order {
+		return cmp.Compare(o, order), nil
+	}
+
+	switch x := goVal.(type) {
+	case bool:
+		return cmp.Compare(boolOrder(bool(t.Value.(Boolean))), boolOrder(x)), nil
+	case int:
+		return compareNumberInt64(t.Value.(Number), int64(x))
+	case int64:
+		return compareNumberInt64(t.Value.(Number), x)
+	case float64:
+		// Integral floats within the range in which float64 represents all
+		// integers exactly compare like the corresponding int64.
+		if x == math.Trunc(x) && math.Abs(x) <= 1<<53 {
+			return compareNumberInt64(t.Value.(Number), int64(x))
+		}
+		return CompareErr(t.Value, floatNumber(x))
+	case string:
+		return strings.Compare(string(t.Value.(String)), x), nil
+	}
+	return 0, nil
+}
+
+func compareTermGoSlow(t *Term, goVal any) (int, error) {
+	v, err := InterfaceToValue(goVal)
+	if err != nil {
+		return 0, err
+	}
+	return CompareErr(t, v)
+}
+
+func compareNumberInt64(n Number, i int64) (int, error) {
+	if ni, ok := numberInt64(n); ok {
+		return cmp.Compare(ni, i), nil
+	}
+	return CompareErr(n, int64Number(i))
+}
+
+func boolOrder(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
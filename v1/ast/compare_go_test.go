@@ -0,0 +1,134 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestCompareJSON(t *testing.T) {
+	tests := []struct {
+		a, b any
+		exp  int
+	}{
+		{float64(1), json.Number("1"), 0},
+		{int64(2), json.Number("1.5"), 1},
+		{[]any{"a", 1}, []any{"a", json.Number("1.0")}, 0},
+		{map[string]any{"a": float64(1)}, map[string]any{"a": json.Number("2")}, -1},
+		{nil, false, -1},
+	}
+	for _, tc := range tests {
+		act, err := CompareJSON(tc.a, tc.b)
+		if err != nil {
+			t.Fatalf("unexpected error for %v and %v: %v", tc.a, tc.b, err)
+		}
+		if act != tc.exp {
+			t.Errorf("expected CompareJSON(%v, %v) == %d but got %d", tc.a, tc.b, tc.exp, act)
+		}
+	}
+
+	if _, err := CompareJSON(map[string]any{"a": make(chan int)}, 1); err == nil {
+		t.Error("expected error for channel")
+	}
+	if _, err := CompareJSON(1, func() {}); err == nil {
+		t.Error("expected error for func")
+	}
+}
+
+func TestCompareTermGo(t *testing.T) {
+	terms := []*Term{
+		nil,
+		{},
+		NullTerm(),
+		BooleanTerm(false),
+		BooleanTerm(true),
+		IntNumberTerm(-3),
+		IntNumberTerm(0),
+		IntNumberTerm(2),
+		NumberTerm("2.0"),
+		NumberTerm("1.5"),
+		NumberTerm("1e3"),
+		NumberTerm("9223372036854775808"),
+		StringTerm(""),
+		StringTerm("a"),
+		StringTerm("b"),
+		VarTerm("x"),
+		ArrayTerm(IntNumberTerm(1)),
+		ObjectTerm(Item(StringTerm("a"), IntNumberTerm(1))),
+	}
+	vals := []any{
+		nil, false, true,
+		0, -3, 2, int64(1000), int64(math.MaxInt64),
+		float64(0), math.Copysign(0, -1), 1.5, 2.0, 1e3, 0.1, 1e300, 9223372036854775808.0,
+		math.Inf(1), math.Inf(-1), math.NaN(),
+		"", "a", "b",
+		[]any{json.Number("1")}, map[string]any{"a": 1}, json.Number("1.50"), uint64(3),
+	}
+	for _, term := range terms {
+		for _, v := range vals {
+			x, err := InterfaceToValue(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp := Compare(term, x)
+			act, err := CompareTermGo(term, v)
+			if err != nil {
+				t.Fatalf("unexpected error for %v and %v: %v", term, v, err)
+			}
+			if act != exp {
+				t.Errorf("expected CompareTermGo(%v, %#v) == %d but got %d", term, v, exp, act)
+			}
+		}
+	}
+
+	if _, err := CompareTermGo(IntNumberTerm(1), make(chan int)); err == nil {
+		t.Error("expected error for channel")
+	}
+
+	for _, tc := range []struct {
+		term *Term
+		v    any
+	}{
+		{IntNumberTerm(42), 42},
+		{IntNumberTerm(42), int64(7)},
+		{IntNumberTerm(42), 42.0},
+		{StringTerm("a"), "b"},
+		{BooleanTerm(true), false},
+		{NullTerm(), nil},
+		{StringTerm("a"), 1},
+	} {
+		if allocs := testing.AllocsPerRun(100, func() { CompareTermGo(tc.term, tc.v) }); allocs != 0 {
+			t.Errorf("expected no allocations comparing %v and %#v but got %v", tc.term, tc.v, allocs)
+		}
+	}
+}
+
+func BenchmarkCompareTermGo(b *testing.B) {
+	terms := make([]*Term, 1000)
+	for i := range terms {
+		terms[i] = IntNumberTerm(i)
+	}
+
+	b.Run("convert", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := range b.N {
+			v, err := InterfaceToValue(500)
+			if err != nil {
+				b.Fatal(err)
+			}
+			Compare(terms[i%len(terms)], NewTerm(v))
+		}
+	})
+	b.Run("direct", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := range b.N {
+			if _, err := CompareTermGo(terms[i%len(terms)], 500); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,44 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+// CompareMemo caches the results of Compare for pairs of terms, keyed by the
+// identity of the terms rather than their values. This speeds up workloads
+// that compare the same large terms over and over, e.g. fixpoint iterations
+// that keep most of their state between iterations. The least recently used
+// results are evicted once the cache is full.
+//
+// Terms must not be modified while a CompareMemo holds results for them, as
+// the cached results would be stale. A CompareMemo is not safe for concurrent
+// use.
+type CompareMemo struct {
+	cache *lruCache[[2]*Term, int]
+}
+
+// NewCompareMemo returns a CompareMemo that caches the results for up to size
+// pairs of terms. If size is not positive, nothing is cached.
+func NewCompareMemo(size int) *CompareMemo {
+	return &CompareMemo{cache: newLRUCache[[2]*Term, int](size)}
+}
+
+// Compare returns Compare(a, b), using a cached result if a and b have been
+// compared before.
+func (m *CompareMemo) Compare(a, b *Term) int {
+	if a == b {
+		return 0
+	}
+	key := [2]*Term{a, b}
+	if cmp, ok := m.cache.get(key); ok {
+		return cmp
+	}
+	cmp := Compare(a, b)
+	m.cache.put(key, cmp)
+	return cmp
+}
+
+// Len returns the number of cached results.
+func (m *CompareMemo) Len() int {
+	return m.cache.len()
+}
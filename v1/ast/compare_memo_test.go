@@ -0,0 +1,89 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestCompareMemo(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	pool := make([]*Term, 20)
+	for i := range pool {
+		if i > 0 && rng.Intn(4) == 0 {
+			pool[i] = NewTerm(respell(rng, pool[i-1].Value))
+		} else {
+			pool[i] = NewTerm(GenRandomValue(rng, 3))
+		}
+	}
+
+	for _, size := range []int{0, 1, 10, 1000} {
+		m := NewCompareMemo(size)
+		for range 2000 {
+			a, b := pool[rng.Intn(len(pool))], pool[rng.Intn(len(pool))]
+			if exp, act := Compare(a, b), m.Compare(a, b); exp != act {
+				t.Fatalf("Expected %d for %v and %v but got %d", exp, a, b, act)
+			}
+			if m.Len() > max(size, 0) {
+				t.Fatalf("Expected at most %d cached results but got %d", size, m.Len())
+			}
+		}
+	}
+}
+
+func TestCompareMemoEviction(t *testing.T) {
+	a, b, c := IntNumberTerm(1), IntNumberTerm(2), IntNumberTerm(3)
+	m := NewCompareMemo(2)
+	m.Compare(a, b)
+	m.Compare(a, c)
+	m.Compare(a, b) // a, b is now the most recently used
+	m.Compare(b, c) // evicts a, c
+
+	if m.Len() != 2 {
+		t.Fatalf("Expected 2 cached results but got %d", m.Len())
+	}
+	for _, key := range [][2]*Term{{a, b}, {b, c}} {
+		if _, ok := m.cache.entries[key]; !ok {
+			t.Errorf("Expected result for %v to be cached", key)
+		}
+	}
+	if _, ok := m.cache.entries[[2]*Term{a, c}]; ok {
+		t.Error("Expected least recently used result to be evicted")
+	}
+
+	if m.Compare(a, a) != 0 || m.Len() != 2 {
+		t.Fatal("Expected identical terms to be equal without caching")
+	}
+}
+
+func BenchmarkCompareMemo(b *testing.B) {
+	// A few large terms, half of which are equal by value, that are compared
+	// over and over.
+	pool := make([]*Term, 8)
+	for i := range pool {
+		elems := make([]*Term, 1000)
+		for j := range elems {
+			elems[j] = ObjectTerm(Item(StringTerm("k"), StringTerm(strconv.Itoa(j))))
+		}
+		if i%2 == 1 {
+			elems[len(elems)-1] = ObjectTerm(Item(StringTerm("k"), StringTerm(strconv.Itoa(i))))
+		}
+		pool[i] = ArrayTerm(elems...)
+	}
+
+	b.Run("Compare", func(b *testing.B) {
+		for i := range b.N {
+			Compare(pool[i%len(pool)], pool[(i/len(pool))%len(pool)])
+		}
+	})
+	b.Run("CompareMemo", func(b *testing.B) {
+		m := NewCompareMemo(128)
+		for i := range b.N {
+			m.Compare(pool[i%len(pool)], pool[(i/len(pool))%len(pool)])
+		}
+	})
+}
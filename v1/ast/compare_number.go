@@ -0,0 +1,509 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+func compareNumbers(a, b Number) int {
+	c, ok := tryCompareNumbers(a, b)
+	if !ok {
+		panic("illegal value")
+	}
+	return c
+}
+
+// tryCompareNumbers compares a and b by their numeric value. It returns false
+// if either is malformed.
+func tryCompareNumbers(a, b Number) (int, bool) {
+	// This only applies if both numbers are integers within the range of
+	// int64, written in plain decimal notation with an optional fraction of
+	// zeros, e.g. 5, 05 or 5.00. Any other spelling, e.g. 1e2, or integers
+	// beyond int64, is compared exactly below.
+	if ai, ok := numberInt64(a); ok {
+		if bi, ok := numberInt64(b); ok {
+			return cmp.Compare(ai, bi), true
+		}
+	}
+
+	// Non-finite numbers cannot be parsed into a big.Rat, so they're
+	// ordered by rank instead: -Inf < finite < +Inf < NaN. Two NaNs compare
+	// equal so that sorting stays stable.
+	ra, nfa := nonFiniteRank(a)
+	rb, nfb := nonFiniteRank(b)
+	if nfa || nfb {
+		return cmp.Compare(ra, rb), true
+	}
+
+	// Most numbers differ in sign or magnitude, which is cheap to compare
+	// without parsing them into a big.Rat. This also guards against numbers
+	// with huge exponents, whose big.Rat representations can take a lot of
+	// time and memory to compute.
+	if da, ok := parseDecimal(a); ok {
+		if db, ok := parseDecimal(b); ok {
+			da, okA := checkDecimal(a, da)
+			db, okB := checkDecimal(b, db)
+			if !okA || !okB {
+				return 0, false
+			}
+			if c, ok := compareDecimals(da, db); ok {
+				return c, true
+			}
+		}
+	}
+
+	if numberCompareCacheEnabled.Load() {
+		return compareNumbersCached(a, b)
+	}
+	return compareNumberRats(a, b)
+}
+
+// malformedNumber returns whichever of a and b tryCompareNumbers failed on.
+func malformedNumber(a, b Number) Number {
+	if _, ok := tryCompareNumbers(a, a); !ok {
+		return a
+	}
+	return b
+}
+
+func compareNumberRats(a, b Number) (int, bool) {
+	x, ok := tryNumberRat(a)
+	if !ok {
+		return 0, false
+	}
+	y, ok := tryNumberRat(b)
+	if !ok {
+		return 0, false
+	}
+	return x.Cmp(y), true
+}
+
+// numberInt64 returns n as an int64 if it is an integer written in plain
+// decimal notation that fits into an int64. Unlike Number.Int64, it also
+// accepts a fraction of zeros, so 5.0 and 5.00 are returned as 5.
+func numberInt64(n Number) (int64, bool) {
+	s := string(n)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		if i == len(s)-1 {
+			return 0, false
+		}
+		for j := i + 1; j < len(s); j++ {
+			if s[j] != '0' {
+				return 0, false
+			}
+		}
+		s = s[:i]
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	return i, err == nil
+}
+
+// decimalExpLimit is the largest exponent, in magnitude, of numbers whose
+// digits are compared by parsing them into a big.Rat. The cost of that grows
+// with the exponent, so numbers with larger exponents are compared digit by
+// digit instead.
+const decimalExpLimit = 1000
+
+// decimalFloatExpLimit is a bound on the exponents of numbers that big.Float
+// can represent: its binary exponents are int32s, which cover decimal
+// exponents up to about 646456993 in magnitude.
+const decimalFloatExpLimit = 600_000_000
+
+// decimal is a number in decimal notation, split into its sign and the
+// significant digits of its magnitude, so that it can be compared without
+// allocating. Its value is sign × 0.hi lo × 10^exp, where hi and lo are the
+// digits before and after the decimal point without leading or trailing
+// zeros.
+type decimal struct {
+	sign   int // -1, 0 or 1
+	hi, lo string
+	exp    int64
+}
+
+func (d decimal) digit(i int) byte {
+	if i < len(d.hi) {
+		return d.hi[i]
+	}
+	return d.lo[i-len(d.hi)]
+}
+
+func (d decimal) numDigits() int {
+	return len(d.hi) + len(d.lo)
+}
+
+// parseDecimal parses n if it is written in decimal notation, i.e. an
+// optional sign, digits with an optional fraction, and an optional exponent.
+// The exponent saturates far beyond the range of big.Float, so that it cannot
+// overflow.
+func parseDecimal(n Number) (decimal, bool) {
+	s := string(n)
+	var d decimal
+	d.sign = 1
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		if s[0] == '-' {
+			d.sign = -1
+		}
+		s = s[1:]
+	}
+
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	intPart := s[:i]
+	var frac string
+	if i < len(s) && s[i] == '.' {
+		j := i + 1
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		frac, i = s[i+1:j], j
+	}
+	if intPart == "" && frac == "" {
+		return decimal{}, false
+	}
+
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		neg := false
+		if i < len(s) && (s[i] == '-' || s[i] == '+') {
+			neg = s[i] == '-'
+			i++
+		}
+		if i == len(s) {
+			return decimal{}, false
+		}
+		for ; i < len(s); i++ {
+			if s[i] < '0' || s[i] > '9' {
+				return decimal{}, false
+			}
+			if d.exp < 1e15 {
+				d.exp = d.exp*10 + int64(s[i]-'0')
+			}
+		}
+		if neg {
+			d.exp = -d.exp
+		}
+	}
+	if i != len(s) {
+		return decimal{}, false
+	}
+
+	// Move the decimal point in front of the first significant digit.
+	d.hi = strings.TrimLeft(intPart, "0")
+	d.lo = strings.TrimRight(frac, "0")
+	if d.hi != "" {
+		d.exp += int64(len(d.hi))
+		if d.lo == "" {
+			d.hi = strings.TrimRight(d.hi, "0")
+		}
+	} else {
+		n := len(d.lo)
+		d.lo = strings.TrimLeft(d.lo, "0")
+		d.exp -= int64(n - len(d.lo))
+	}
+	if d.numDigits() == 0 {
+		d.sign = 0
+	}
+	return d, true
+}
+
+// compareDecimals compares a and b, parsed into x and y, by their signs and
+// magnitudes. It returns false if they are equal in both, unless their
+// exponents exceed decimalExpLimit, in which case their digits are compared
+// as well.
+//
+// Both must have been checked with checkDecimal.
+func compareDecimals(x, y decimal) (int, bool) {
+	if c := cmp.Compare(x.sign, y.sign); c != 0 || x.sign == 0 {
+		return c, true
+	}
+	if c := cmp.Compare(x.exp, y.exp); c != 0 {
+		return c * x.sign, true
+	}
+	if x.exp < -decimalExpLimit || x.exp > decimalExpLimit {
+		return compareDigits(x, y) * x.sign, true
+	}
+	return 0, false
+}
+
+// compareDigits compares the digits of x and y lexicographically, which with
+// equal exponents is how their magnitudes compare, e.g. 0.12 < 0.123 < 0.2.
+func compareDigits(x, y decimal) int {
+	n, m := x.numDigits(), y.numDigits()
+	for i := range min(n, m) {
+		if c := cmp.Compare(x.digit(i), y.digit(i)); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(n, m)
+}
+
+// checkDecimal checks d, parsed from n, like parseNumberRat does if its
+// exponent is close to the limits of big.Float: numbers beyond its range are
+// rejected, and numbers that it rounds to zero are returned as zero.
+func checkDecimal(n Number, d decimal) (decimal, bool) {
+	if d.exp >= -decimalFloatExpLimit && d.exp <= decimalFloatExpLimit {
+		return d, true
+	}
+	f, ok := new(big.Float).SetString(string(n))
+	if !ok {
+		return decimal{}, false
+	}
+	if f.Sign() == 0 {
+		return decimal{}, true
+	}
+	return d, true
+}
+
+// hugeDecimal returns n parsed into a decimal if its exponent exceeds
+// decimalExpLimit, so that it can be handled digit by digit like
+// compareDecimals does, without computing its big.Rat representation. Since
+// numbers that compare equal have equal exponents, either all or none of them
+// are huge.
+func hugeDecimal(n Number) (decimal, bool) {
+	d, ok := parseDecimal(n)
+	if !ok || d.exp >= -decimalExpLimit && d.exp <= decimalExpLimit {
+		return decimal{}, false
+	}
+	d, ok = checkDecimal(n, d)
+	return d, ok && d.sign != 0
+}
+
+// numberRatCacheSize is the number of slots in numberRatCache. It must be a
+// power of two.
+const numberRatCacheSize = 4096
+
+type numberRatCacheEntry struct {
+	num Number
+	rat *big.Rat
+}
+
+// numberRatCache is a direct-mapped cache of parsed numbers, used to avoid
+// re-parsing the same Number on every comparison when sorting. Colliding
+// entries simply replace each other, so memory use is bounded. Cached values
+// are shared and must never be mutated.
+var numberRatCache [numberRatCacheSize]atomic.Pointer[numberRatCacheEntry]
+
+// numberRat returns the big.Rat representation of n, consulting and filling
+// numberRatCache. The returned value must not be modified. numberRat panics if
+// n is malformed.
+func numberRat(n Number) *big.Rat {
+	r, ok := tryNumberRat(n)
+	if !ok {
+		panic("illegal value")
+	}
+	return r
+}
+
+// tryNumberRat is like numberRat, but returns false if n is malformed.
+func tryNumberRat(n Number) (*big.Rat, bool) {
+	slot := &numberRatCache[xxhash.Sum64String(string(n))&(numberRatCacheSize-1)]
+	if e := slot.Load(); e != nil && e.num == n {
+		return e.rat, true
+	}
+	r, ok := parseNumberRat(n)
+	if !ok {
+		return nil, false
+	}
+	slot.Store(&numberRatCacheEntry{num: n, rat: r})
+	return r, true
+}
+
+// numberCompareCacheSize is the number of results kept by the number compare
+// cache.
+const numberCompareCacheSize = 4096
+
+// numberCompareCacheEnabled guards numberCompareCache, so that comparisons
+// only pay for an atomic load while the cache is disabled.
+var numberCompareCacheEnabled atomic.Bool
+
+// numberCompareCache maps pairs of numbers to the result of comparing them
+// exactly.
+var numberCompareCache = struct {
+	sync.Mutex
+	cache *lruCache[[2]Number, int]
+}{cache: newLRUCache[[2]Number, int](numberCompareCacheSize)}
+
+// EnableNumberCompareCache turns caching of the results of comparing numbers
+// on or off. The cache only applies to numbers that cannot be compared as
+// int64s, e.g. decimals and numbers in exponent notation, and that do not
+// already differ in sign or magnitude, e.g. 0.5 and 0.75. It holds the
+// results for the most recently compared 4096 pairs. It helps workloads that
+// repeatedly compare the same numbers, e.g. against constant thresholds. The
+// cache is disabled by default, and disabling it clears it.
+func EnableNumberCompareCache(enabled bool) {
+	numberCompareCache.Lock()
+	defer numberCompareCache.Unlock()
+	numberCompareCacheEnabled.Store(enabled)
+	if !enabled {
+		numberCompareCache.cache.clear()
+	}
+}
+
+func compareNumbersCached(a, b Number) (int, bool) {
+	key := [2]Number{a, b}
+	numberCompareCache.Lock()
+	c, ok := numberCompareCache.cache.get(key)
+	numberCompareCache.Unlock()
+	if ok {
+		return c, true
+	}
+
+	c, ok = compareNumberRats(a, b)
+	if !ok {
+		return 0, false
+	}
+
+	numberCompareCache.Lock()
+	if _, ok := numberCompareCache.cache.get(key); !ok && numberCompareCacheEnabled.Load() {
+		numberCompareCache.cache.put(key, c)
+	}
+	numberCompareCache.Unlock()
+	return c, true
+}
+
+func parseNumberRat(n Number) (*big.Rat, bool) {
+	// We use big.Rat for comparing big numbers.
+	// It replaces big.Float due to following reason:
+	// big.Float comes with a default precision of 64, and setting a
+	// larger precision results in more memory being allocated
+	// (regardless of the actual number we are parsing with SetString).
+	//
+	// Note: If we're so close to zero that big.Float says we are zero, do
+	// *not* big.Rat).SetString on the original string it'll potentially
+	// take very long.
+	f, ok := new(big.Float).SetString(string(n))
+	if !ok {
+		return nil, false
+	}
+	if f.IsInt() {
+		if i, _ := f.Int64(); i == 0 {
+			return new(big.Rat).SetInt64(0), true
+		}
+	}
+	r, ok := new(big.Rat).SetString(string(n))
+	if !ok {
+		return nil, false
+	}
+	return r, true
+}
+
+// CompareNumberApprox compares a and b like Compare, except that numbers whose
+// difference is at most epsilon are considered equal. A nil or zero epsilon
+// results in an exact comparison. CompareNumberApprox panics if epsilon is
+// negative.
+func CompareNumberApprox(a, b Number, epsilon *big.Rat) int {
+	if epsilon == nil || epsilon.Sign() == 0 {
+		return compareNumbers(a, b)
+	}
+	if epsilon.Sign() < 0 {
+		panic(fmt.Sprintf("illegal epsilon: %v", epsilon.RatString()))
+	}
+	_, nfa := nonFiniteRank(a)
+	_, nfb := nonFiniteRank(b)
+	if nfa || nfb {
+		return compareNumbers(a, b)
+	}
+	diff := new(big.Rat).Sub(numberRat(a), numberRat(b))
+	sign := diff.Sign()
+	if diff.Abs(diff).Cmp(epsilon) <= 0 {
+		return 0
+	}
+	return sign
+}
+
+// CompareNumberSigned compares a and b like Compare, except that negative zero
+// is less than positive zero, so -0 < 0 and -0.0 < 0.0. Zeros of the same sign
+// are still equal regardless of their spelling, e.g. 0, 0.0 and 0e10.
+func CompareNumberSigned(a, b Number) int {
+	c := compareNumbers(a, b)
+	if c != 0 || !numberIsZero(a) {
+		return c
+	}
+	return cmp.Compare(numberSignBit(b), numberSignBit(a))
+}
+
+// numberIsZero returns true if n is a finite number equal to zero.
+func numberIsZero(n Number) bool {
+	if i, ok := n.Int64(); ok {
+		return i == 0
+	}
+	if _, ok := nonFiniteRank(n); ok {
+		return false
+	}
+	return numberRat(n).Sign() == 0
+}
+
+// numberSignBit returns 1 if n is spelled with a minus sign and 0 otherwise.
+func numberSignBit(n Number) int {
+	if strings.HasPrefix(string(n), "-") {
+		return 1
+	}
+	return 0
+}
+
+// CompareJSONNumber compares a and b after rounding them to float64, which is
+// the precision encoding/json uses when decoding numbers into interface{}
+// values. Unlike Compare, which is exact, numbers that only differ beyond
+// float64 precision (roughly 15 to 17 significant digits, or integers beyond
+// ±2^53) compare equal, as do numbers too small to be distinguished from zero.
+// Numbers too large for float64 round to ±Inf. This makes sorting idempotent
+// across a JSON serialization round-trip.
+func CompareJSONNumber(a, b Number) int {
+	fa, fb := numberFloat64(a), numberFloat64(b)
+	// NaN sorts last, like in Compare.
+	switch na, nb := math.IsNaN(fa), math.IsNaN(fb); {
+	case na && nb:
+		return 0
+	case na:
+		return 1
+	case nb:
+		return -1
+	}
+	return cmp.Compare(fa, fb)
+}
+
+// numberFloat64 returns n rounded to the nearest float64, and ±Inf if it is
+// out of range.
+func numberFloat64(n Number) float64 {
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		if e, ok := err.(*strconv.NumError); !ok || e.Err != strconv.ErrRange {
+			panic(&UnsupportedValueError{Value: n})
+		}
+	}
+	return f
+}
+
+// nonFiniteRank returns the sort rank of n if it spells out an infinity or
+// NaN, as some JSON encoders produce: -1 for -Inf, 1 for +Inf and 2 for NaN.
+// Finite numbers have rank 0 and false is returned.
+func nonFiniteRank(n Number) (int, bool) {
+	if len(n) == 0 || (n[0] >= '0' && n[0] <= '9') {
+		return 0, false
+	}
+	s := string(n)
+	switch {
+	case strings.EqualFold(s, "-inf"), strings.EqualFold(s, "-infinity"):
+		return -1, true
+	case strings.EqualFold(s, "inf"), strings.EqualFold(s, "+inf"),
+		strings.EqualFold(s, "infinity"), strings.EqualFold(s, "+infinity"):
+		return 1, true
+	case strings.EqualFold(s, "nan"), strings.EqualFold(s, "+nan"), strings.EqualFold(s, "-nan"):
+		return 2, true
+	}
+	return 0, false
+}
@@ -0,0 +1,456 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCompareNonFiniteNumbers(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  int
+	}{
+		{"-Inf", "-Inf", 0},
+		{"-Inf", "-1e308", -1},
+		{"-Inf", "0", -1},
+		{"-Inf", "+Inf", -1},
+		{"-Inf", "NaN", -1},
+		{"1e308", "Inf", -1},
+		{"123456789123456789123.5", "+Inf", -1},
+		{"Inf", "+Inf", 0},
+		{"Infinity", "inf", 0},
+		{"Inf", "NaN", -1},
+		{"NaN", "NaN", 0},
+		{"NaN", "nan", 0},
+		{"NaN", "1", 1},
+		{"NaN", "-Inf", 1},
+		{"0.5", "NaN", -1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.a+"_"+tc.b, func(t *testing.T) {
+			if act := Compare(Number(tc.a), Number(tc.b)); act != tc.exp {
+				t.Errorf("Expected Compare(%v, %v) == %d but got %d", tc.a, tc.b, tc.exp, act)
+			}
+			if act := Compare(Number(tc.b), Number(tc.a)); act != -tc.exp {
+				t.Errorf("Expected Compare(%v, %v) == %d but got %d", tc.b, tc.a, -tc.exp, act)
+			}
+		})
+	}
+}
+
+func BenchmarkCompareSortDecimals(b *testing.B) {
+	rng := rand.New(rand.NewSource(42))
+	nums := make([]*Term, 10000)
+	for i := range nums {
+		nums[i] = NumberTerm(json.Number(strconv.FormatFloat(rng.Float64()*1000, 'f', 6, 64)))
+	}
+	terms := make([]*Term, len(nums))
+
+	// parse is the baseline of parsing both numbers on every comparison.
+	b.Run("parse", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			copy(terms, nums)
+			slices.SortFunc(terms, func(x, y *Term) int {
+				rx, _ := parseNumberRat(x.Value.(Number))
+				ry, _ := parseNumberRat(y.Value.(Number))
+				return rx.Cmp(ry)
+			})
+		}
+	})
+	b.Run("Compare", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			copy(terms, nums)
+			slices.SortFunc(terms, func(x, y *Term) int { return Compare(x, y) })
+		}
+	})
+}
+
+func TestCompareNumbersCached(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	nums := make([]Number, 2*numberRatCacheSize)
+	for i := range nums {
+		nums[i] = Number(strconv.FormatFloat(rng.NormFloat64()*1e6, 'f', rng.Intn(8)+1, 64))
+	}
+
+	// Run twice so that the second pass hits (and collides in) the cache.
+	for range 2 {
+		for i := 1; i < len(nums); i++ {
+			a, b := nums[i-1], nums[i]
+			ra, _ := parseNumberRat(a)
+			rb, _ := parseNumberRat(b)
+			exp := ra.Cmp(rb)
+			if act := Compare(a, b); act != exp {
+				t.Fatalf("Expected Compare(%v, %v) == %d but got %d", a, b, exp, act)
+			}
+		}
+	}
+}
+
+func TestNumberCompareCache(t *testing.T) {
+	EnableNumberCompareCache(true)
+	t.Cleanup(func() { EnableNumberCompareCache(false) })
+
+	// Numbers of the same sign and magnitude, since only those are compared
+	// exactly, and thus cached.
+	rng := rand.New(rand.NewSource(7))
+	nums := make([]Number, 100)
+	for i := range nums {
+		nums[i] = Number(strconv.FormatFloat(1e3+rng.Float64()*9e3, 'f', rng.Intn(4)+1, 64))
+	}
+	nums = append(nums, "1e2", "100.0", "0.1e3", "-0.0", "1", "Inf", "NaN")
+
+	// Compare every pair twice, so that the second pass is answered by the
+	// cache.
+	for range 2 {
+		for _, a := range nums {
+			for _, b := range nums {
+				exp := CompareJSONNumber(a, b)
+				if act := Compare(a, b); act != exp {
+					t.Fatalf("Expected Compare(%v, %v) == %d but got %d", a, b, exp, act)
+				}
+			}
+		}
+	}
+
+	if n := numberCompareCache.cache.len(); n != numberCompareCacheSize {
+		t.Fatalf("Expected %d cached results but got %d", numberCompareCacheSize, n)
+	}
+	EnableNumberCompareCache(false)
+	if n := numberCompareCache.cache.len(); n != 0 {
+		t.Fatalf("Expected cache to be cleared but got %d cached results", n)
+	}
+}
+
+func TestCompareNumbersHugeExponents(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  int
+	}{
+		{"1e100000000", "2e100000000", -1},
+		{"2e100000000", "1e100000000", 1},
+		{"1e100000000", "10e99999999", 0},
+		{"0.12e100000000", "0.123e100000000", -1},
+		{"0.2e100000000", "0.123e100000000", 1},
+		{"-1e100000000", "-2e100000000", 1},
+		{"1e100000000", "-1e100000000", 1},
+		{"1e100000000", "1e100000001", -1},
+		{"1e100000000", "1", 1},
+		{"-1e100000000", "1", -1},
+		{"1e-100000000", "2e-100000000", -1},
+		{"1e-100000000", "0", 1},
+		{"-1e-100000000", "0", -1},
+		{"1e-100000000", "1", -1},
+		{"1e999999", "1.0000000001e999999", -1},
+		{"1e999999", "1e999998", 1},
+		{"0e99999999999", "0", 0},
+		{"630E-840354372", "0", 0},
+		{"1e100000000", "Inf", -1},
+		{"-1e100000000", "-Inf", 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.a+" "+tc.b, func(t *testing.T) {
+			if act := Compare(Number(tc.a), Number(tc.b)); act != tc.exp {
+				t.Fatalf("Expected %d but got %d", tc.exp, act)
+			}
+			if act := Compare(Number(tc.b), Number(tc.a)); act != -tc.exp {
+				t.Fatalf("Expected %d when swapped but got %d", -tc.exp, act)
+			}
+		})
+	}
+
+	// Numbers beyond the range of big.Float are still rejected.
+	if _, err := CompareErr(Number("1e9999999999"), Number("1")); err == nil {
+		t.Fatal("Expected error")
+	}
+
+	// Comparing numbers with huge exponents must not materialize them.
+	for _, p := range [][2]Number{
+		{"1e999999", "2e999999"},
+		{"1e999999", "-1e999999"},
+		{"1e999999", "1e-999999"},
+		{"1e100000000", "1.5e100000000"},
+	} {
+		allocs := testing.AllocsPerRun(100, func() {
+			x, _ := parseDecimal(p[0])
+			y, _ := parseDecimal(p[1])
+			x, _ = checkDecimal(p[0], x)
+			y, _ = checkDecimal(p[1], y)
+			compareDecimals(x, y)
+		})
+		if allocs != 0 {
+			t.Fatalf("Expected no allocations comparing %v and %v but got %v", p[0], p[1], allocs)
+		}
+	}
+}
+
+func BenchmarkNumberCompareCache(b *testing.B) {
+	// Numbers compared against a fixed threshold, as in policies like
+	// `input.score > 0.75`.
+	rng := rand.New(rand.NewSource(42))
+	nums := make([]Number, 100)
+	for i := range nums {
+		nums[i] = Number(strconv.FormatFloat(rng.Float64(), 'f', 2, 64))
+	}
+	threshold := Number("0.75")
+
+	for _, enabled := range []bool{false, true} {
+		b.Run(fmt.Sprintf("enabled=%v", enabled), func(b *testing.B) {
+			EnableNumberCompareCache(enabled)
+			defer EnableNumberCompareCache(false)
+			b.ReportAllocs()
+			for i := range b.N {
+				Compare(nums[i%len(nums)], threshold)
+			}
+		})
+	}
+}
+
+func TestCompareNumberApprox(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		epsilon string
+		exp     int
+	}{
+		{"1.0000000001", "1.0", "1e-9", 0},
+		{"1.0", "1.0000000001", "1e-9", 0},
+		{"1.0000000001", "1.0", "1e-11", 1},
+		{"1.0", "1.0000000001", "1e-11", -1},
+		{"1.0000000001", "1.0", "0", 1},
+		{"1", "1.0", "0", 0},
+		{"1.5", "1", "0.5", 0},
+		{"1.6", "1", "0.5", 1},
+		{"-Inf", "1", "1e300", -1},
+		{"NaN", "NaN", "1", 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.a+"_"+tc.b+"_"+tc.epsilon, func(t *testing.T) {
+			eps, ok := new(big.Rat).SetString(tc.epsilon)
+			if !ok {
+				t.Fatalf("bad epsilon %v", tc.epsilon)
+			}
+			if act := CompareNumberApprox(Number(tc.a), Number(tc.b), eps); act != tc.exp {
+				t.Errorf("Expected %d but got %d", tc.exp, act)
+			}
+		})
+	}
+
+	if act := CompareNumberApprox(Number("1.0000000001"), Number("1"), nil); act != 1 {
+		t.Errorf("Expected nil epsilon to compare exactly but got %d", act)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected panic on negative epsilon")
+		}
+	}()
+	CompareNumberApprox(Number("1"), Number("2"), big.NewRat(-1, 10))
+}
+
+func TestCompareJSONNumber(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  int
+	}{
+		{"1", "1.0", 0},
+		{"1", "2", -1},
+		{"0.1", "0.10000000000000001", 0},           // same float64
+		{"0.1", "0.1000000000000001", -1},           // next float64 up
+		{"9007199254740992", "9007199254740993", 0}, // 2^53 and 2^53+1
+		{"9007199254740992", "9007199254740994", -1},
+		{"123456789123456789123", "123456789123456789122", 0},
+		{"1e-400", "0", 0},
+		{"-1e-400", "0", 0},
+		{"1e400", "1e401", 0},
+		{"1e400", "Inf", 0},
+		{"-1e400", "-1e308", -1},
+		{"1e308", "NaN", -1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.a+"_"+tc.b, func(t *testing.T) {
+			if act := CompareJSONNumber(Number(tc.a), Number(tc.b)); act != tc.exp {
+				t.Errorf("Expected CompareJSONNumber(%v, %v) == %d but got %d", tc.a, tc.b, tc.exp, act)
+			}
+			if act := CompareJSONNumber(Number(tc.b), Number(tc.a)); act != -tc.exp {
+				t.Errorf("Expected CompareJSONNumber(%v, %v) == %d but got %d", tc.b, tc.a, -tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestCompareJSONNumberRoundTrip(t *testing.T) {
+	nums := []Number{
+		"9007199254740993", "9007199254740992", "9007199254740991",
+		"0.30000000000000004", "0.3", "0.1", "0.10000000000000001",
+		"1.7976931348623157e308", "4.9e-324", "5e-324", "0", "-0",
+		"123456789.123456789123", "123456789.12345679",
+	}
+
+	sortJSON := func(ns []Number) {
+		slices.SortStableFunc(ns, CompareJSONNumber)
+	}
+
+	sortJSON(nums)
+	roundTripped := make([]Number, len(nums))
+	for i, n := range nums {
+		bs, err := json.Marshal(MustJSON(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var f float64
+		if err := json.Unmarshal(bs, &f); err != nil {
+			t.Fatal(err)
+		}
+		roundTripped[i] = floatNumber(f)
+	}
+
+	resorted := slices.Clone(roundTripped)
+	sortJSON(resorted)
+	for i := range roundTripped {
+		if roundTripped[i] != resorted[i] {
+			t.Fatalf("Expected sort to be idempotent after round-trip, got %v and %v", roundTripped, resorted)
+		}
+		if CompareJSONNumber(nums[i], roundTripped[i]) != 0 {
+			t.Fatalf("Expected %v to survive round-trip but got %v", nums[i], roundTripped[i])
+		}
+	}
+}
+
+func TestCompareNumbersIntegerFractions(t *testing.T) {
+	tests := []struct {
+		a, b Number
+		exp  int
+	}{
+		{"5", "5.0", 0},
+		{"5", "5.00", 0},
+		{"5.0", "5.00", 0},
+		{"-5.0", "-5", 0},
+		{"5.0", "6", -1},
+		{"5.00", "4.0", 1},
+		{"5.01", "5", 1},
+		{"5.0e1", "50.0", 0},
+		{"9223372036854775807.0", "9223372036854775807", 0},
+		{"9223372036854775808.0", "9223372036854775807", 1},
+	}
+	for _, tc := range tests {
+		if result := Compare(tc.a, tc.b); result != tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", tc.exp, tc.a, tc.b, result)
+		}
+		if result := Compare(tc.b, tc.a); result != -tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", -tc.exp, tc.b, tc.a, result)
+		}
+	}
+
+	// Integers with a fraction of zeros take the int64 fast path.
+	for _, n := range []Number{"5", "5.0", "5.00", "-5.000", "05.0"} {
+		if i, ok := numberInt64(n); !ok || (i != 5 && i != -5) {
+			t.Errorf("expected %v to be an int64 but got %d, %v", n, i, ok)
+		}
+	}
+	for _, n := range []Number{"5.", ".0", "5.01", "5.0e1", "1e2", "9223372036854775808.0"} {
+		if _, ok := numberInt64(n); ok {
+			t.Errorf("expected %v not to be an int64", n)
+		}
+	}
+}
+
+func TestCompareNumbersExponentIntegers(t *testing.T) {
+	tests := []struct {
+		a, b Number
+		exp  int
+	}{
+		{"1E2", "100", 0},
+		{"1.0e2", "100", 0},
+		{"1E2", "1.0e2", 0},
+		{"0100", "100", 0},
+		{"+100", "1e+2", 0},
+		{"1e18", "1000000000000000000", 0},
+		{"1e18", "999999999999999999", 1},
+		{"1e19", "9223372036854775807", 1},
+		{"9223372036854775807", "9.223372036854775807e18", 0},
+		{"9223372036854775807", "9223372036854775808", -1},
+		{"9.223372036854775808e18", "9223372036854775807", 1},
+		{"-9223372036854775808", "-9.223372036854775808e18", 0},
+		{"-9223372036854775809", "-9223372036854775808", -1},
+		{"-9.223372036854775809e18", "-9223372036854775808", -1},
+		{"18446744073709551616", "1.8446744073709551616e19", 0},
+	}
+
+	for _, tc := range tests {
+		if result := Compare(tc.a, tc.b); result != tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", tc.exp, tc.a, tc.b, result)
+		}
+		if result := Compare(tc.b, tc.a); result != -tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", -tc.exp, tc.b, tc.a, result)
+		}
+		if tc.exp != 0 {
+			continue
+		}
+		if Hash(tc.a) != Hash(tc.b) {
+			t.Errorf("expected equal hashes for %v and %v", tc.a, tc.b)
+		}
+
+		// Sets and objects must treat both spellings as the same element or
+		// key, regardless of which one is inserted first.
+		for _, x := range [][2]Number{{tc.a, tc.b}, {tc.b, tc.a}} {
+			set := NewSet(NewTerm(x[0]), NewTerm(x[1]))
+			if set.Len() != 1 || !set.Contains(NewTerm(x[1])) {
+				t.Errorf("expected set with single element for %v and %v but got %v", x[0], x[1], set)
+			}
+			obj := NewObject(Item(NewTerm(x[0]), BooleanTerm(true)))
+			if obj.Get(NewTerm(x[1])) == nil {
+				t.Errorf("expected %v to find key %v", obj, x[1])
+			}
+		}
+	}
+}
+
+func TestCompareNumberSigned(t *testing.T) {
+	zeros := []Number{"-0", "0", "0.0", "-0.0"}
+	for _, a := range zeros {
+		for _, b := range zeros {
+			if act := Compare(a, b); act != 0 {
+				t.Errorf("expected Compare(%v, %v) == 0 but got %d", a, b, act)
+			}
+			exp := 0
+			switch {
+			case strings.HasPrefix(string(a), "-") && !strings.HasPrefix(string(b), "-"):
+				exp = -1
+			case !strings.HasPrefix(string(a), "-") && strings.HasPrefix(string(b), "-"):
+				exp = 1
+			}
+			if act := CompareNumberSigned(a, b); act != exp {
+				t.Errorf("expected CompareNumberSigned(%v, %v) == %d but got %d", a, b, exp, act)
+			}
+		}
+	}
+
+	tests := []struct {
+		a, b Number
+		exp  int
+	}{
+		{"-1", "-0", -1},
+		{"-0", "1e-10", -1},
+		{"-0e5", "0e-5", -1},
+		{"1", "1.0", 0},
+	}
+	for _, tc := range tests {
+		if act := CompareNumberSigned(tc.a, tc.b); act != tc.exp {
+			t.Errorf("expected CompareNumberSigned(%v, %v) == %d but got %d", tc.a, tc.b, tc.exp, act)
+		}
+	}
+}
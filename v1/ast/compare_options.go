@@ -0,0 +1,138 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+)
+
+// CompareOptions configures CompareWith. The zero value makes CompareWith
+// behave exactly like Compare. Options are only ever added as fields whose
+// zero value keeps that behavior, so CompareOptions literals should use field
+// names.
+//
+// Compare never considers locations, so there is no option for them. See
+// EqualIgnoreLocation to also compare the comments of modules.
+type CompareOptions struct {
+	// IgnoreAnnotations ignores the annotations of modules and rules.
+	IgnoreAnnotations bool
+
+	// AlphaEquivalence treats comprehensions that only differ in the names of
+	// the variables they declare as equal, like CompareAlphaEquiv. If a and b
+	// are bodies or expressions, this applies to them as well.
+	AlphaEquivalence bool
+
+	// NumberEpsilon, if not nil, treats numbers whose difference is at most
+	// NumberEpsilon as equal, like CompareNumberApprox. Note that this makes
+	// equality intransitive.
+	NumberEpsilon *big.Rat
+
+	// SignedZero orders negative zero before positive zero, like
+	// CompareNumberSigned. It is ignored for numbers that are only equal
+	// because of NumberEpsilon.
+	SignedZero bool
+
+	// StringFold orders strings case-insensitively, like CompareStringFold.
+	// Strings that only differ in case are still not equal.
+	StringFold bool
+
+	// SetsByCardinality orders sets by their number of elements first, like
+	// CompareSetByCardinality.
+	SetsByCardinality bool
+
+	// NullsLast orders null after all other values, like NULLS LAST in SQL,
+	// instead of before them. This applies to nested values as well. The
+	// order of all other values is unchanged.
+	NullsLast bool
+}
+
+// CompareWith compares a and b like Compare, with the behavior adjusted by
+// opts.
+func CompareWith(a, b any, opts CompareOptions) int {
+	if opts == (CompareOptions{}) {
+		return Compare(a, b)
+	}
+	if opts.AlphaEquivalence {
+		a, b = alphaCanonical(a), alphaCanonical(b)
+	}
+	c := comparer{opts: opts}
+	return c.compare(a, b)
+}
+
+// compareCtxInterval is the number of values that CompareCtx compares between
+// checks of its context. BenchmarkCompareCtx shows no measurable difference
+// between intervals of 64 and 65536, so this mainly bounds how long it takes
+// to notice cancellation.
+const compareCtxInterval = 1024
+
+// CompareCtx compares a and b like Compare, but checks ctx periodically while
+// doing so, and returns ctx.Err() if ctx is done before the comparison
+// finishes. Like CompareErr, it returns an *UnsupportedValueError instead of
+// panicking on values that cannot be compared.
+//
+// CompareCtx is slower than Compare and only worth using for very large
+// values, e.g. sets with millions of elements.
+func CompareCtx(ctx context.Context, a, b any) (res int, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			switch e := r.(type) {
+			case *UnsupportedValueError:
+				err = e
+			case compareCanceledError:
+				err = e.err
+			default:
+				panic(r)
+			}
+		}
+	}()
+	c := comparer{ctx: ctx, errors: true}
+	return c.compare(a, b), nil
+}
+
+// MaxDepthError is returned by CompareDepth when the values it compares are
+// nested deeper than MaxDepth.
+type MaxDepthError struct {
+	MaxDepth int
+}
+
+func (e *MaxDepthError) Error() string {
+	return fmt.Sprintf("maximum comparison depth of %d exceeded", e.MaxDepth)
+}
+
+// CompareDepth compares a and b like Compare, but returns a *MaxDepthError
+// instead of recursing into values nested more than maxDepth levels below a
+// and b, e.g. 1 in [[1]] is nested 2 levels deep. Compare itself has no limit,
+// so values that are nested deeply enough can exhaust the stack; CompareDepth
+// is meant for values from untrusted sources. Only the values actually
+// compared count, so values that differ early can still be compared even if
+// they are nested more deeply elsewhere. Like CompareErr, it returns an
+// *UnsupportedValueError instead of panicking on values that cannot be
+// compared.
+func CompareDepth(a, b any, maxDepth int) (res int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch e := r.(type) {
+			case *UnsupportedValueError:
+				err = e
+			case *MaxDepthError:
+				err = e
+			default:
+				panic(r)
+			}
+		}
+	}()
+	c := comparer{limitDepth: true, maxDepth: maxDepth, errors: true}
+	return c.compare(a, b), nil
+}
+
+// compareCanceledError is used to unwind comparer when its context is done.
+type compareCanceledError struct {
+	err error
+}
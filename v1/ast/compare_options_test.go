@@ -0,0 +1,369 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math/big"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestCompareWithZeroOptions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 1000 {
+		a, b := GenRandomValue(rng, 3), GenRandomValue(rng, 3)
+		if exp, act := Compare(a, b), CompareWith(a, b, CompareOptions{}); exp != act {
+			t.Fatalf("expected %d for %v and %v but got %d", exp, a, b, act)
+		}
+	}
+
+	a := MustParseModule("package a\n\np := [x | some x in input]")
+	b := MustParseModule("package a\n\np := [y | some y in input]")
+	if exp, act := Compare(a, b), CompareWith(a, b, CompareOptions{}); exp != act {
+		t.Fatalf("expected %d but got %d", exp, act)
+	}
+}
+
+func TestCompareWithIgnoreAnnotations(t *testing.T) {
+	parse := func(s string) *Module {
+		return MustParseModuleWithOpts(s, ParserOptions{ProcessAnnotation: true})
+	}
+	a := parse("# METADATA\n# title: a\npackage a\n\n# METADATA\n# title: p\np := 1")
+	b := parse("# METADATA\n# title: b\npackage a\n\np := 1")
+
+	if CompareWith(a, b, CompareOptions{}) == 0 {
+		t.Fatal("expected modules with different annotations to differ")
+	}
+	if CompareWith(a, b, CompareOptions{IgnoreAnnotations: true}) != 0 {
+		t.Fatal("expected modules to be equal when ignoring annotations")
+	}
+	if CompareWith(a.Rules[0], b.Rules[0], CompareOptions{IgnoreAnnotations: true}) != 0 {
+		t.Fatal("expected rules to be equal when ignoring annotations")
+	}
+
+	c := parse("package a\n\np := 2")
+	if CompareWith(a, c, CompareOptions{IgnoreAnnotations: true}) >= 0 {
+		t.Fatal("expected modules with different rules to differ")
+	}
+}
+
+func TestCompareWithAlphaEquivalence(t *testing.T) {
+	opts := CompareOptions{AlphaEquivalence: true}
+
+	a := MustParseModule("package a\n\np := {k: [x | some x in input[k]] | some k in input}")
+	b := MustParseModule("package a\n\np := {j: [y | some y in input[j]] | some j in input}")
+	if CompareWith(a, b, CompareOptions{}) == 0 {
+		t.Fatal("expected modules to differ without alpha equivalence")
+	}
+	if CompareWith(a, b, opts) != 0 {
+		t.Fatal("expected modules to be equal with alpha equivalence")
+	}
+
+	c := MustParseModule("package a\n\np := {j: [y | some y in input[k]] | some j in input}")
+	if CompareWith(a, c, opts) == 0 {
+		t.Fatal("expected modules with different free variables to differ")
+	}
+
+	x, y := MustParseBody("some x; p[x]; x > 1"), MustParseBody("some y; p[y]; y > 1")
+	if CompareWith(x, y, opts) != 0 {
+		t.Fatal("expected bodies to be equal with alpha equivalence")
+	}
+}
+
+func TestCompareWithNumberEpsilon(t *testing.T) {
+	opts := CompareOptions{NumberEpsilon: big.NewRat(1, 1000)}
+
+	tests := []struct {
+		a, b string
+		exp  int
+	}{
+		{`1`, `1.0005`, 0},
+		{`1`, `1.002`, -1},
+		{`[1, {"a": 2.0001}]`, `[1, {"a": 2}]`, 0},
+		{`{1.0001, 3}`, `{1, 3}`, 0},
+	}
+
+	for _, tc := range tests {
+		a, b := MustParseTerm(tc.a), MustParseTerm(tc.b)
+		if result := CompareWith(a, b, opts); result != tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", tc.exp, a, b, result)
+		}
+	}
+
+	a := MustParseModule("package a\n\np := 0.3")
+	b := MustParseModule("package a\n\np := 0.3000001")
+	if CompareWith(a, b, opts) != 0 || CompareWith(a, b, CompareOptions{}) == 0 {
+		t.Fatal("expected numbers in rules to be compared with epsilon")
+	}
+}
+
+func TestCompareWithSetsByCardinality(t *testing.T) {
+	opts := CompareOptions{SetsByCardinality: true}
+	a, b := MustParseTerm(`[{1, 3}]`), MustParseTerm(`[{2}]`)
+
+	if CompareWith(a, b, CompareOptions{}) >= 0 {
+		t.Fatal("expected {1, 3} to be less than {2} by default")
+	}
+	if CompareWith(a, b, opts) <= 0 {
+		t.Fatal("expected {1, 3} to be greater than {2} by cardinality")
+	}
+	if CompareWith(MustParseTerm(`{1, 2}`), MustParseTerm(`{1, 3}`), opts) >= 0 {
+		t.Fatal("expected sets of equal size to be compared by elements")
+	}
+}
+
+func TestCompareWithNullsLast(t *testing.T) {
+	terms := []*Term{
+		MustParseTerm(`"a"`),
+		NullTerm(),
+		MustParseTerm(`[null, 1]`),
+		MustParseTerm(`1`),
+		MustParseTerm(`false`),
+		NullTerm(),
+		MustParseTerm(`[1, null]`),
+		MustParseTerm(`{"a": null}`),
+		MustParseTerm(`{"a": 1}`),
+	}
+
+	tests := []struct {
+		opts CompareOptions
+		exp  []string
+	}{
+		{
+			opts: CompareOptions{},
+			exp:  []string{`null`, `null`, `false`, `1`, `"a"`, `[null, 1]`, `[1, null]`, `{"a": null}`, `{"a": 1}`},
+		},
+		{
+			opts: CompareOptions{NullsLast: true},
+			exp:  []string{`false`, `1`, `"a"`, `[1, null]`, `[null, 1]`, `{"a": 1}`, `{"a": null}`, `null`, `null`},
+		},
+	}
+	for _, tc := range tests {
+		sorted := slices.Clone(terms)
+		slices.SortStableFunc(sorted, func(a, b *Term) int { return CompareWith(a, b, tc.opts) })
+		for i, s := range tc.exp {
+			if !sorted[i].Equal(MustParseTerm(s)) {
+				t.Fatalf("expected %v with %+v but got %v", tc.exp, tc.opts, sorted)
+			}
+		}
+	}
+
+	if CompareWith(nil, NullTerm(), CompareOptions{NullsLast: true}) >= 0 {
+		t.Fatal("expected nil to be less than null")
+	}
+}
+
+func TestCompareWithSignedZero(t *testing.T) {
+	opts := CompareOptions{SignedZero: true, NumberEpsilon: big.NewRat(1, 1000)}
+	tests := []struct {
+		a, b string
+		exp  int
+	}{
+		{`-0`, `0`, -1},
+		{`[-0.0]`, `[0.0001]`, 0},
+		{`{"a": 0}`, `{"a": -0e3}`, 1},
+	}
+	for _, tc := range tests {
+		a, b := MustParseTerm(tc.a), MustParseTerm(tc.b)
+		if result := CompareWith(a, b, opts); result != tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", tc.exp, a, b, result)
+		}
+	}
+}
+
+// countdownContext is canceled once Err has been called n times.
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (c *countdownContext) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestCompareCtx(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 1000 {
+		a, b := GenRandomValue(rng, 3), GenRandomValue(rng, 3)
+		act, err := CompareCtx(context.Background(), a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := Compare(a, b); act != exp {
+			t.Fatalf("expected %d for %v and %v but got %d", exp, a, b, act)
+		}
+	}
+
+	if _, err := CompareCtx(context.Background(), Number("x"), Number("1")); err == nil {
+		t.Fatal("expected error for malformed number")
+	}
+}
+
+func TestCompareCtxCanceled(t *testing.T) {
+	build := func() Set {
+		set := NewSet()
+		for i := range 100_000 {
+			set.Add(IntNumberTerm(i))
+		}
+		set.Slice() // sort before comparing
+		return set
+	}
+	a, b := build(), build()
+
+	ctx := &countdownContext{Context: context.Background(), n: 10}
+	if _, err := CompareCtx(ctx, a, b); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled but got %v", err)
+	}
+	if ctx.n != -1 {
+		t.Fatalf("expected comparison to stop at the first check after cancellation, but %d checks remain", ctx.n)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CompareCtx(canceled, a, b); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled but got %v", err)
+	}
+}
+
+func TestCompareOptionsLazyObjectsCyclic(t *testing.T) {
+	direct := func(v any) map[string]any {
+		m := map[string]any{"v": v}
+		m["self"] = m
+		return m
+	}
+	wrapped := func(v any) map[string]any {
+		m := map[string]any{"v": v}
+		m["self"] = LazyObject(m)
+		return m
+	}
+	compareFuncs := map[string]func(a, b Value) (int, error){
+		"CompareWith": func(a, b Value) (int, error) {
+			return CompareWith(a, b, CompareOptions{StringFold: true}), nil
+		},
+		"CompareCtx": func(a, b Value) (int, error) {
+			return CompareCtx(context.Background(), a, b)
+		},
+	}
+
+	for fname, f := range compareFuncs {
+		for name, mk := range map[string]func(any) map[string]any{"direct": direct, "wrapped": wrapped} {
+			t.Run(fname+"/"+name, func(t *testing.T) {
+				a, b, c := LazyObject(mk(1)), LazyObject(mk(1)), LazyObject(mk(2))
+				for _, tc := range []struct {
+					x, y Value
+					exp  int
+				}{
+					{a, a, 0},
+					{a, b, 0},
+					{a, c, -1},
+					{c, a, 1},
+				} {
+					act, err := f(tc.x, tc.y)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if cmp.Compare(act, 0) != tc.exp {
+						t.Errorf("expected %d for %v and %v but got %d", tc.exp, tc.x, tc.y, act)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCompareDepth(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		maxDepth int
+		exp      int
+		err      bool
+	}{
+		{`1`, `2`, 0, -1, false},
+		{`[1]`, `[2]`, 0, 0, true},
+		{`[[1]]`, `[[2]]`, 1, 0, true},
+		{`[[1]]`, `[[2]]`, 2, -1, false},
+		{`{"a": {"b": 1}}`, `{"a": {"b": 2}}`, 2, -1, false},
+		{`{"a": {"b": 1}}`, `{"a": {"b": 2}}`, 1, 0, true},
+		{`{{1}}`, `{{2}}`, 1, 0, true},
+		{`[1, [[[1]]]]`, `[2, [[[1]]]]`, 1, -1, false},
+		{`[x | x = [[1]]]`, `[x | x = [[2]]]`, 4, -1, false},
+		{`[x | x = [[1]]]`, `[x | x = [[2]]]`, 3, 0, true},
+	}
+	for _, tc := range tests {
+		a, b := MustParseTerm(tc.a), MustParseTerm(tc.b)
+		c, err := CompareDepth(a, b, tc.maxDepth)
+		if tc.err {
+			var derr *MaxDepthError
+			if !errors.As(err, &derr) || derr.MaxDepth != tc.maxDepth {
+				t.Errorf("expected *MaxDepthError for %v and %v with depth %d but got %v", a, b, tc.maxDepth, err)
+			}
+			continue
+		}
+		if err != nil || c != tc.exp {
+			t.Errorf("expected %d for %v and %v with depth %d but got %d, %v", tc.exp, a, b, tc.maxDepth, c, err)
+		}
+	}
+
+	if _, err := CompareDepth(Number("x"), Number("1"), 10); err == nil || err.Error() != `illegal value: ast.Number "x"` {
+		t.Fatalf("expected illegal value error but got %v", err)
+	}
+}
+
+func TestCompareDepthDeeplyNested(t *testing.T) {
+	const depth = 100_000
+	build := func(leaf int) *Term {
+		term := IntNumberTerm(leaf)
+		for range depth {
+			term = ArrayTerm(term)
+		}
+		return term
+	}
+	a, b := build(1), build(2)
+
+	_, err := CompareDepth(a, b, 1000)
+	var derr *MaxDepthError
+	if !errors.As(err, &derr) {
+		t.Fatalf("expected *MaxDepthError but got %v", err)
+	}
+	if err.Error() != "maximum comparison depth of 1000 exceeded" {
+		t.Fatalf("unexpected error message: %v", err)
+	}
+	if c, err := CompareDepth(a, b, depth); err != nil || c != -1 {
+		t.Fatalf("expected -1 within the maximum depth but got %d, %v", c, err)
+	}
+}
+
+func BenchmarkCompareCtx(b *testing.B) {
+	build := func() Set {
+		set := NewSet()
+		for i := range 100_000 {
+			set.Add(ArrayTerm(IntNumberTerm(i), StringTerm("x")))
+		}
+		set.Slice()
+		return set
+	}
+	x, y := build(), build()
+
+	b.Run("Compare", func(b *testing.B) {
+		for range b.N {
+			Compare(x, y)
+		}
+	})
+	b.Run("CompareCtx", func(b *testing.B) {
+		ctx := context.Background()
+		for range b.N {
+			if _, err := CompareCtx(ctx, x, y); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
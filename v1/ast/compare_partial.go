@@ -0,0 +1,99 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "cmp"
+
+// ComparePartial compares a and b like Compare, except that a variable for
+// which wildcards returns true matches any value, including composite values
+// and other variables, at any position inside refs, calls, arrays, objects and
+// sets. The returned bool is true if a and b compare equal only because of
+// such a match. If wildcards is nil, Var.IsWildcard is used, which matches
+// both _ and generated variables.
+//
+// Object keys and set elements are matched pairwise in sorted order, so a
+// wildcard used as an object key or set element only matches the key or
+// element at the same position on the other side. Since wildcards are equal
+// to everything, ComparePartial is not transitive and must not be used for
+// sorting.
+func ComparePartial(a, b Value, wildcards func(Var) bool) (int, bool) {
+	if wildcards == nil {
+		wildcards = Var.IsWildcard
+	}
+	p := partialComparer{wildcards: wildcards}
+	if c := p.compare(a, b); c != 0 {
+		return c, false
+	}
+	return 0, p.matched
+}
+
+type partialComparer struct {
+	wildcards func(Var) bool
+	matched   bool
+}
+
+func (p *partialComparer) isWildcard(v Value) bool {
+	x, ok := v.(Var)
+	return ok && p.wildcards(x)
+}
+
+func (p *partialComparer) compare(a, b Value) int {
+	if p.isWildcard(a) || p.isWildcard(b) {
+		p.matched = true
+		return 0
+	}
+	switch x := a.(type) {
+	case Ref:
+		if y, ok := b.(Ref); ok {
+			return p.termSlice(x, y)
+		}
+	case Call:
+		if y, ok := b.(Call); ok {
+			return p.termSlice(x, y)
+		}
+	case *Array:
+		if y, ok := b.(*Array); ok {
+			return p.termSlice(x.elems, y.elems)
+		}
+	case Object:
+		if y, ok := b.(Object); ok {
+			return p.object(x, y)
+		}
+	case Set:
+		if y, ok := b.(Set); ok {
+			return p.termSlice(x.Slice(), y.Slice())
+		}
+	}
+	return Compare(a, b)
+}
+
+func (p *partialComparer) term(a, b *Term) int {
+	if a == nil || b == nil || a.Value == nil || b.Value == nil {
+		return Compare(a, b)
+	}
+	return p.compare(a.Value, b.Value)
+}
+
+func (p *partialComparer) termSlice(a, b []*Term) int {
+	for i := range min(len(a), len(b)) {
+		if c := p.term(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func (p *partialComparer) object(a, b Object) int {
+	aKeys, bKeys := a.Keys(), b.Keys()
+	for i := range min(len(aKeys), len(bKeys)) {
+		if c := p.term(aKeys[i], bKeys[i]); c != 0 {
+			return c
+		}
+		if c := p.term(a.Get(aKeys[i]), b.Get(bKeys[i])); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(aKeys), len(bKeys))
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "testing"
+
+func TestComparePartial(t *testing.T) {
+	tests := []struct {
+		note      string
+		a, b      string
+		wildcards func(Var) bool
+		exp       int
+		matched   bool
+	}{
+		{note: "ground equal", a: `[1, "a"]`, b: `[1, "a"]`, exp: 0},
+		{note: "ground less", a: `[1, "a"]`, b: `[1, "b"]`, exp: -1},
+		{note: "wildcard scalar", a: `_`, b: `1`, exp: 0, matched: true},
+		{note: "wildcard right", a: `{"a": 1}`, b: `_`, exp: 0, matched: true},
+		{note: "wildcard vs var", a: `_`, b: `x`, exp: 0, matched: true},
+		{note: "plain vars", a: `x`, b: `y`, exp: -1},
+		{note: "array some positions", a: `[1, _, 3]`, b: `[1, [2, 2], 3]`, exp: 0, matched: true},
+		{note: "array mismatch after wildcard", a: `[1, _, 3]`, b: `[1, 2, 4]`, exp: -1},
+		{note: "array length", a: `[1, _]`, b: `[1, 2, 3]`, exp: -1},
+		{note: "nested", a: `[{"a": [_, 2]}]`, b: `[{"a": [1, 2]}]`, exp: 0, matched: true},
+		{note: "object values", a: `{"a": _, "b": 2}`, b: `{"a": 1, "b": 2}`, exp: 0, matched: true},
+		{note: "object keys differ", a: `{"a": _}`, b: `{"b": 1}`, exp: -1},
+		{note: "object size", a: `{"a": _}`, b: `{"a": 1, "b": 2}`, exp: -1},
+		{note: "set", a: `{1, _}`, b: `{1, 2}`, exp: 0, matched: true},
+		{note: "ref", a: `data.x[_]`, b: `data.x.y`, exp: 0, matched: true},
+		{
+			note:      "custom wildcards",
+			a:         `[x, y]`,
+			b:         `[1, y]`,
+			wildcards: func(v Var) bool { return v == "x" },
+			exp:       0,
+			matched:   true,
+		},
+		{
+			note:      "custom wildcards excludes _",
+			a:         `[_, 1]`,
+			b:         `[1, 1]`,
+			wildcards: func(v Var) bool { return v == "x" },
+			exp:       1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			a := MustParseTerm(tc.a).Value
+			b := MustParseTerm(tc.b).Value
+			result, matched := ComparePartial(a, b, tc.wildcards)
+			if result != tc.exp || matched != tc.matched {
+				t.Fatalf("expected (%d, %v) for %v and %v but got (%d, %v)", tc.exp, tc.matched, a, b, result, matched)
+			}
+			result, matched = ComparePartial(b, a, tc.wildcards)
+			if result != -tc.exp || matched != tc.matched {
+				t.Fatalf("expected (%d, %v) for %v and %v but got (%d, %v)", -tc.exp, tc.matched, b, a, result, matched)
+			}
+		})
+	}
+}
@@ -0,0 +1,474 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+)
+
+// CompareImportsSemantic compares a and b like the imports of modules are
+// compared by Compare, but independently of their order, and ignoring aliases
+// that don't change the name an import is referred to by: import data.foo.bar
+// and import data.foo.bar as bar are equal. If ignoreAliases is true, imports
+// are compared by path only, so import data.foo.bar as x and import
+// data.foo.bar as y are equal as well. Both slices are sorted before comparing
+// them. Neither a nor b is modified.
+func CompareImportsSemantic(a, b []*Import, ignoreAliases bool) int {
+	f := importCompareSemantic
+	if ignoreAliases {
+		f = importComparePath
+	}
+	return slices.CompareFunc(sortedImports(a, f), sortedImports(b, f), f)
+}
+
+func importCompareSemantic(a, b *Import) int {
+	if c := importComparePath(a, b); c != 0 || a == nil || b == nil {
+		return c
+	}
+	return VarCompare(a.Name(), b.Name())
+}
+
+func importComparePath(a, b *Import) int {
+	if a == nil || b == nil {
+		return a.Compare(b)
+	}
+	return Compare(a.Path, b.Path)
+}
+
+func sortedImports(imports []*Import, f func(a, b *Import) int) []*Import {
+	if len(imports) < 2 {
+		return imports
+	}
+	sorted := slices.Clone(imports)
+	slices.SortFunc(sorted, f)
+	return sorted
+}
+
+// CompareAnnotationsSemantic compares a and b like the annotations of modules
+// are compared by Compare, but independently of the order in which they occur.
+// Both slices are sorted by scope and target path (see
+// Annotations.GetTargetPath) before comparing them. Annotations with the same
+// scope and target path are ordered by title and then by their remaining
+// content, as defined by Annotations.Compare. Neither a nor b is modified.
+func CompareAnnotationsSemantic(a, b []*Annotations) int {
+	return annotationsCompare(sortedAnnotations(a), sortedAnnotations(b))
+}
+
+func sortedAnnotations(as []*Annotations) []*Annotations {
+	if len(as) < 2 {
+		return as
+	}
+	sorted := slices.Clone(as)
+	slices.SortFunc(sorted, func(x, y *Annotations) int {
+		if x == nil || y == nil {
+			return x.Compare(y)
+		}
+		if cmp := scopeCompare(x.Scope, y.Scope); cmp != 0 {
+			return cmp
+		}
+		if cmp := termSliceCompare(x.GetTargetPath(), y.GetTargetPath()); cmp != 0 {
+			return cmp
+		}
+		return x.Compare(y)
+	})
+	return sorted
+}
+
+// ComparePackagePath orders packages by their paths, component by component,
+// like Package.Compare: data.a.b sorts before data.a.c and before data.a.b.x,
+// so sorting packages groups each package with the packages nested in it.
+// Unlike Package.Compare, a path head given as a String is treated like the
+// equally named Var, as by RefCompareNormalized, and nil packages sort first.
+func ComparePackagePath(a, b *Package) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return RefCompareNormalized(a.Path, b.Path)
+}
+
+// CompareHeadKind compares a and b like Head.Compare, except that heads of
+// different kinds are ordered by their kind first: complete rules (including
+// constants like p := 1) < partial set rules < partial object rules (including
+// rules with dynamic refs) < functions. The returned bool is true if a and b
+// differ in their kind, and false if they are of the same kind, regardless of
+// whether they differ otherwise.
+func CompareHeadKind(a, b *Head) (int, bool) {
+	if a == nil || b == nil {
+		return a.Compare(b), false
+	}
+	if ka, kb := headKind(a), headKind(b); ka != kb {
+		return cmp.Compare(ka, kb), true
+	}
+	return a.Compare(b), false
+}
+
+// headKind returns the rank of head's kind in the ordering of
+// CompareHeadKind.
+func headKind(head *Head) int {
+	if len(head.Args) > 0 {
+		return 3
+	}
+	switch head.DocKind() {
+	case PartialSetDoc:
+		return 1
+	case PartialObjectDoc:
+		return 2
+	}
+	return 0
+}
+
+// CompareRuleByName orders rules by their head refs first, as defined by
+// RefCompareNormalized, then by their number of arguments, and then by the arguments
+// themselves. For rules with the same name and arguments, default rules sort
+// before other rules. Everything else, e.g. the bodies and values of the
+// rules, is ignored, so all rules defining the same function are adjacent when
+// sorted, ordered by arity. Nil rules sort first.
+func CompareRuleByName(a, b *Rule) int {
+	if a == nil || b == nil {
+		return a.Compare(b)
+	}
+	if c := RefCompareNormalized(a.Head.Ref(), b.Head.Ref()); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(len(a.Head.Args), len(b.Head.Args)); c != 0 {
+		return c
+	}
+	if c := termSliceCompare(a.Head.Args, b.Head.Args); c != 0 {
+		return c
+	}
+	if a.Default != b.Default {
+		if a.Default {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// CompareExprCommutative compares a and b like Compare, but ignores the order
+// of the operands of commutative built-in functions, as reported by
+// Builtin.IsCommutative, so `x == y` and `y == x`, or `x + y` and `y + x`,
+// are equal. The operands of such calls are sorted by Compare before
+// comparing, including calls that are themselves operands, as in
+// `1 == x + y`. Other operators, e.g. `<` and `-`, are compared in order.
+func CompareExprCommutative(a, b *Expr) int {
+	return commutativeExpr(a).Compare(commutativeExpr(b))
+}
+
+// commutativeExpr returns e with the operands of commutative calls sorted. e
+// is only copied if operands are reordered.
+func commutativeExpr(e *Expr) *Expr {
+	if e == nil {
+		return nil
+	}
+	terms, ok := e.Terms.([]*Term)
+	if !ok || len(terms) == 0 {
+		return e
+	}
+	sorted := commutativeCall(terms)
+	if &sorted[0] == &terms[0] {
+		return e
+	}
+	cpy := *e
+	cpy.Terms = sorted
+	return &cpy
+}
+
+// commutativeCall returns the terms of a call with the first two operands
+// sorted if its operator is a commutative built-in function. The terms are
+// only copied if an operand is reordered, either in terms itself or in a call
+// nested in its operands.
+func commutativeCall(terms []*Term) []*Term {
+	result := terms
+	for i := 1; i < len(terms); i++ {
+		call, ok := terms[i].Value.(Call)
+		if !ok || len(call) == 0 {
+			continue
+		}
+		sorted := commutativeCall(call)
+		if &sorted[0] == &call[0] {
+			continue
+		}
+		if &result[0] == &terms[0] {
+			result = slices.Clone(terms)
+		}
+		cpy := *terms[i]
+		cpy.Value = Call(sorted)
+		result[i] = &cpy
+	}
+
+	if len(result) < 3 {
+		return result
+	}
+	op, ok := result[0].Value.(Ref)
+	if !ok {
+		return result
+	}
+	if bi, ok := BuiltinMap[op.String()]; !ok || !bi.IsCommutative() {
+		return result
+	}
+	if Compare(result[1], result[2]) > 0 {
+		if &result[0] == &terms[0] {
+			result = slices.Clone(terms)
+		}
+		result[1], result[2] = result[2], result[1]
+	}
+	return result
+}
+
+// CompareSomeDeclSet compares a and b like Compare, but independently of the
+// order of the variables they declare, so `some x, y` and `some y, x` are
+// equal. The order of declared variables has no meaning in Rego: it only
+// introduces them into the local scope.
+//
+// The order is significant for `some k, v in c`, which binds k to a key and v
+// to a value of c, and which is represented as a single call to
+// internal.member_3. Such declarations, and the key and value of every
+// expressions for the same reason, are compared like Compare. Nil
+// declarations sort first.
+func CompareSomeDeclSet(a, b *SomeDecl) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return termSliceCompare(sortedSomeDeclSymbols(a), sortedSomeDeclSymbols(b))
+}
+
+// sortedSomeDeclSymbols returns the symbols of d sorted if they are all
+// variables.
+func sortedSomeDeclSymbols(d *SomeDecl) []*Term {
+	for _, sym := range d.Symbols {
+		if _, ok := sym.Value.(Var); !ok {
+			return d.Symbols
+		}
+	}
+	symbols := slices.Clone(d.Symbols)
+	slices.SortFunc(symbols, TermValueCompare)
+	return symbols
+}
+
+// CompareBodyUnordered compares a and b like Compare, but independently of the
+// order of their expressions, so `a == 1; b == 2` and `b == 2; a == 1` are
+// equal. The expressions of both bodies are sorted by Compare, ignoring their
+// indices, before comparing them. Bodies nested in comprehensions or every
+// expressions are still compared in order.
+//
+// This is a syntactic equivalence only: it does not consider data
+// dependencies between expressions, e.g. assignments that must precede the
+// expressions using them, nor whether reordering preserves the result of
+// evaluation.
+func CompareBodyUnordered(a, b Body) int {
+	return sortedBody(a).Compare(sortedBody(b))
+}
+
+func sortedBody(body Body) Body {
+	sorted := make(Body, len(body))
+	for i, expr := range body {
+		if expr != nil {
+			cpy := *expr
+			cpy.Index = 0
+			expr = &cpy
+		}
+		sorted[i] = expr
+	}
+	slices.SortFunc(sorted, (*Expr).Compare)
+	return sorted
+}
+
+// CompareModulesSemantic compares a and b like Module.Compare, but ignores
+// differences that do not affect the meaning of the modules: whether rule
+// heads use = or :=, and the order of annotations. Like Compare, locations and
+// comments are never considered. As a result, a module compares equal to
+// itself after being formatted and parsed again.
+func CompareModulesSemantic(a, b *Module) int {
+	if a == nil {
+		if b == nil {
+			return 0
+		}
+		return -1
+	} else if b == nil {
+		return 1
+	}
+	if cmp := a.Package.Compare(b.Package); cmp != 0 {
+		return cmp
+	}
+	if cmp := importsCompare(a.Imports, b.Imports); cmp != 0 {
+		return cmp
+	}
+	if cmp := CompareAnnotationsSemantic(a.Annotations, b.Annotations); cmp != 0 {
+		return cmp
+	}
+	minLen := min(len(a.Rules), len(b.Rules))
+	for i := range minLen {
+		if cmp := ruleCompareSemantic(a.Rules[i], b.Rules[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return cmp.Compare(len(a.Rules), len(b.Rules))
+}
+
+func ruleCompareSemantic(a, b *Rule) int {
+	if a == nil {
+		if b == nil {
+			return 0
+		}
+		return -1
+	} else if b == nil {
+		return 1
+	}
+	if cmp := headCompareSemantic(a.Head, b.Head); cmp != 0 {
+		return cmp
+	}
+	if a.Default != b.Default {
+		if !a.Default {
+			return -1
+		}
+		return 1
+	}
+	if cmp := a.Body.Compare(b.Body); cmp != 0 {
+		return cmp
+	}
+	if cmp := CompareAnnotationsSemantic(a.Annotations, b.Annotations); cmp != 0 {
+		return cmp
+	}
+	return ruleCompareSemantic(a.Else, b.Else)
+}
+
+func headCompareSemantic(a, b *Head) int {
+	if a == nil || b == nil {
+		return a.Compare(b)
+	}
+	x, y := *a, *b
+	x.Assign, y.Assign = false, false
+	return x.Compare(&y)
+}
+
+// EqualIgnoreLocation returns true if a and b are equal when all Location
+// fields are ignored, e.g. two modules parsed from source that only differs in
+// whitespace. Like Compare, it ignores locations on terms and AST nodes, but
+// unlike Compare it also requires modules to have the same comments. Comments
+// are compared by their text only, while Comment.Equal also compares their
+// locations.
+func EqualIgnoreLocation(a, b any) bool {
+	if Compare(a, b) != 0 {
+		return false
+	}
+	x, ok1 := a.(*Module)
+	y, ok2 := b.(*Module)
+	if !ok1 || !ok2 || x == nil || y == nil {
+		return true
+	}
+	return slices.EqualFunc(x.Comments, y.Comments, func(c, d *Comment) bool {
+		return bytes.Equal(c.Text, d.Text)
+	})
+}
+
+// StripMetadata returns a deep copy of the AST node x, as made by Copy, with
+// the locations of all nodes and terms cleared and all comments removed,
+// including the comments that annotations were parsed from. The contents of
+// annotations are kept, since they are part of the policy.
+//
+// Compare and the Compare methods of nodes already ignore locations and
+// comments, so this is not needed for comparing nodes, but it makes nodes that
+// only differ in their formatting equal in all other respects too, e.g. when
+// marshalled with locations or inspected with reflection.
+func StripMetadata(x any) any {
+	x = Copy(x)
+	var vis *GenericVisitor
+	vis = NewGenericVisitor(func(x any) bool {
+		if n, ok := x.(Node); ok {
+			n.SetLoc(nil)
+		}
+		switch x := x.(type) {
+		case *Module:
+			x.Comments = nil
+		case *Rule:
+			for _, a := range x.Annotations {
+				vis.Walk(a)
+			}
+		case *Head:
+			// Walk only visits the name, args, key and value of heads.
+			vis.Walk(x.Reference)
+		case *Annotations:
+			x.comments = nil
+			for _, s := range x.Schemas {
+				vis.Walk(s.Path)
+				vis.Walk(s.Schema)
+			}
+		}
+		return false
+	})
+	vis.Walk(x)
+	return x
+}
+
+// CompareWithSliceNormalized compares the with modifiers a and b like
+// Compare, but independently of the order of modifiers whose order does not
+// matter, so `with input.x as 1 with input.y as 2` and `with input.y as 2 with
+// input.x as 1` are equal.
+//
+// Modifiers are applied in order, so if the target of one modifier is equal to
+// or a prefix of the target of another, as in `with input as {} with input.x
+// as 1`, the later one overrides (part of) the earlier one and their order is
+// significant. Such modifiers keep their relative order, and all others are
+// sorted by target, then value: the modifiers are put into the least order,
+// according to With.Compare, that keeps the relative order of all pairs of
+// overlapping modifiers. Two slices that only differ in the order of
+// modifiers with disjoint targets are therefore normalized identically.
+func CompareWithSliceNormalized(a, b []*With) int {
+	return withSliceCompare(normalizedWiths(a), normalizedWiths(b))
+}
+
+// normalizedWiths returns a copy of ws in normalized order, as described by
+// CompareWithSliceNormalized. This is the least topological order of the
+// modifiers, where a modifier must come after all overlapping modifiers that
+// preceded it in ws, which is picked greedily.
+func normalizedWiths(ws []*With) []*With {
+	result := make([]*With, 0, len(ws))
+	done := make([]bool, len(ws))
+	for range ws {
+		next := -1
+		for i, w := range ws {
+			if done[i] || (next >= 0 && w.Compare(ws[next]) >= 0) {
+				continue
+			}
+			ready := true
+			for j := range i {
+				if !done[j] && withTargetsOverlap(ws[j], w) {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+			}
+		}
+		done[next] = true
+		result = append(result, ws[next])
+	}
+	return result
+}
+
+// withTargetsOverlap returns true if the target of a is equal to or a prefix of
+// the target of b, or vice versa.
+func withTargetsOverlap(a, b *With) bool {
+	ra, okA := a.Target.Value.(Ref)
+	rb, okB := b.Target.Value.(Ref)
+	if !okA || !okB {
+		return a.Target.Equal(b.Target)
+	}
+	return ra.HasPrefix(rb) || rb.HasPrefix(ra)
+}
@@ -0,0 +1,583 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEqualIgnoreLocation(t *testing.T) {
+	const src = `# METADATA
+# title: test
+package test
+
+import rego.v1
+
+# a comment
+p contains x if {
+	some x in input.xs # trailing
+	x > 1
+}
+
+# METADATA
+# description: q
+q := {"a": [1, 2]}
+`
+	parse := func(s string) *Module {
+		t.Helper()
+		return MustParseModuleWithOpts(s, ParserOptions{ProcessAnnotation: true})
+	}
+
+	a := parse(src)
+	b := parse("\n\n   \n" + src)
+	if a.Comments[0].Location.Equal(b.Comments[0].Location) {
+		t.Fatal("expected locations to differ")
+	}
+	if !EqualIgnoreLocation(a, b) {
+		t.Fatalf("expected modules to be equal ignoring location:\n%v\n\n%v", a, b)
+	}
+	if !EqualIgnoreLocation(a.Rules[0], b.Rules[0]) {
+		t.Fatalf("expected rules to be equal ignoring location")
+	}
+
+	c := parse(strings.Replace(src, "# a comment", "# another comment", 1))
+	if EqualIgnoreLocation(a, c) {
+		t.Fatal("expected modules with different comments not to be equal")
+	}
+
+	d := parse(strings.Replace(src, "x > 1", "x > 2", 1))
+	if EqualIgnoreLocation(a, d) {
+		t.Fatal("expected modules with different rules not to be equal")
+	}
+
+	if !EqualIgnoreLocation((*Module)(nil), (*Module)(nil)) || EqualIgnoreLocation(a, (*Module)(nil)) {
+		t.Fatal("unexpected result for nil modules")
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	const src = `# METADATA
+# title: test
+package test
+
+import rego.v1
+
+# a comment
+p contains x if {
+	some x in input.xs # trailing
+	x > 1
+}
+
+# METADATA
+# description: q
+# schemas:
+#   - input.x: schema.x
+a.b[c] := {"a": [1, 2]} if {
+	c := "y"
+	every y in [1] { y == 1 } with input as {"x": 1}
+}
+
+f(x) := x + 1
+`
+	const reformatted = `# METADATA
+# title: test
+package test
+import rego.v1
+p contains x if { some x in input.xs; x > 1 }
+# METADATA
+# description: q
+# schemas:
+#   - input.x: schema.x
+a.b[c] := {"a": [1, 2]} if {
+	# another comment
+	c := "y"
+	every y in [1] {
+		y == 1
+	} with input as {"x": 1}
+}
+f(x) := x + 1 # trailing
+`
+	parse := func(s string) *Module {
+		t.Helper()
+		return MustParseModuleWithOpts(s, ParserOptions{ProcessAnnotation: true})
+	}
+
+	a, b := parse(src), parse(reformatted)
+	if EqualIgnoreLocation(a, b) {
+		t.Fatal("Expected modules with different comments not to be equal")
+	}
+
+	x := StripMetadata(a).(*Module)
+	y := StripMetadata(b).(*Module)
+	if x.Compare(a) != 0 {
+		t.Fatalf("Expected stripped module to equal original:\n%v\n\n%v", x, a)
+	}
+	if !EqualIgnoreLocation(x, y) {
+		t.Fatalf("Expected stripped modules to be equal:\n%v\n\n%v", x, y)
+	}
+	for _, m := range []*Module{x, y} {
+		if found := findMetadata(reflect.ValueOf(m), "module", map[uintptr]bool{}); len(found) > 0 {
+			t.Fatalf("Expected no locations or comments but found:\n%v", strings.Join(found, "\n"))
+		}
+	}
+	if len(findMetadata(reflect.ValueOf(a), "module", map[uintptr]bool{})) == 0 || len(a.Comments) == 0 {
+		t.Fatal("Expected original module to keep its locations and comments")
+	}
+
+	term := MustParseTerm(`{"a": [x, {1}]}`)
+	stripped := StripMetadata(term).(*Term)
+	if found := findMetadata(reflect.ValueOf(stripped), "term", map[uintptr]bool{}); len(found) > 0 {
+		t.Fatalf("Expected no locations but found:\n%v", strings.Join(found, "\n"))
+	}
+	if term.Location == nil || !term.Equal(stripped) {
+		t.Fatal("Expected a copy of the term")
+	}
+}
+
+// findMetadata returns the paths of all non-nil locations and non-empty
+// comments reachable from v.
+func findMetadata(v reflect.Value, path string, visited map[uintptr]bool) []string {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || visited[v.Pointer()] {
+			return nil
+		}
+		visited[v.Pointer()] = true
+		if v.Type() == reflect.TypeOf(&Location{}) {
+			return []string{path}
+		}
+		return findMetadata(v.Elem(), path, visited)
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return findMetadata(v.Elem(), path, visited)
+	case reflect.Struct:
+		var found []string
+		for i := range v.NumField() {
+			found = append(found, findMetadata(v.Field(i), path+"."+v.Type().Field(i).Name, visited)...)
+		}
+		return found
+	case reflect.Slice, reflect.Array:
+		if v.Type() == reflect.TypeOf([]*Comment{}) && v.Len() > 0 {
+			return []string{path}
+		}
+		var found []string
+		for i := range v.Len() {
+			found = append(found, findMetadata(v.Index(i), fmt.Sprintf("%s[%d]", path, i), visited)...)
+		}
+		return found
+	case reflect.Map:
+		var found []string
+		iter := v.MapRange()
+		for iter.Next() {
+			found = append(found, findMetadata(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), visited)...)
+		}
+		return found
+	}
+	return nil
+}
+
+func TestCompareImportsSemantic(t *testing.T) {
+	imports := func(s string) []*Import {
+		return MustParseModule("package test\n" + s).Imports
+	}
+	tests := []struct {
+		a, b          string
+		exp           int
+		ignoreAliases bool
+	}{
+		{a: "import data.a\nimport data.b", b: "import data.b\nimport data.a", exp: 0},
+		{a: "import data.a.b as b\nimport input.x", b: "import input.x\nimport data.a.b", exp: 0},
+		{a: "import data.a.b as c", b: "import data.a.b", exp: 1},
+		{a: "import data.a.b as c", b: "import data.a.b as d", exp: -1},
+		{a: "import data.a\nimport data.b", b: "import data.a", exp: 1},
+		{a: "import data.a", b: "import data.a\nimport data.a.b", exp: -1},
+		{a: "import data.a.b", b: "import data.a.c", exp: -1},
+		{a: "import data.a.b as c", b: "import data.a.b", exp: 0, ignoreAliases: true},
+		{a: "import data.a.b as c\nimport input.x", b: "import input.x as y\nimport data.a.b as d", exp: 0, ignoreAliases: true},
+		{a: "import data.a.b as c", b: "import data.a.c as b", exp: -1, ignoreAliases: true},
+		{a: "import data.a as x\nimport data.a as y", b: "import data.a", exp: 1, ignoreAliases: true},
+	}
+	for _, tc := range tests {
+		a, b := imports(tc.a), imports(tc.b)
+		if act := CompareImportsSemantic(a, b, tc.ignoreAliases); act != tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", tc.exp, a, b, act)
+		}
+		if act := CompareImportsSemantic(b, a, tc.ignoreAliases); act != -tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", -tc.exp, b, a, act)
+		}
+	}
+
+	a := imports("import data.b\nimport data.a")
+	CompareImportsSemantic(a, nil, false)
+	if a[0].Path.String() != "data.b" {
+		t.Fatal("Expected imports not to be modified")
+	}
+}
+
+func TestCompareAnnotationsSemantic(t *testing.T) {
+	parse := func(s string) *Module {
+		return MustParseModuleWithOpts(s, ParserOptions{ProcessAnnotation: true})
+	}
+	a := parse(`# METADATA
+# title: pkg
+package a
+
+# METADATA
+# title: p
+p := 1
+
+# METADATA
+# title: q
+q := 2
+
+# METADATA
+# scope: document
+# title: r
+r := 3
+`)
+	b := parse(`# METADATA
+# title: pkg
+package a
+
+# METADATA
+# scope: document
+# title: r
+r := 3
+
+# METADATA
+# title: q
+q := 2
+
+# METADATA
+# title: p
+p := 1
+`)
+
+	if annotationsCompare(a.Annotations, b.Annotations) == 0 {
+		t.Fatal("expected annotations in different order to differ")
+	}
+	if act := CompareAnnotationsSemantic(a.Annotations, b.Annotations); act != 0 {
+		t.Fatalf("expected annotations to be equal regardless of order but got %d", act)
+	}
+	if a.Annotations[1].Title != "p" {
+		t.Fatal("expected annotations not to be modified")
+	}
+
+	c := parse(`package a
+
+# METADATA
+# title: p2
+p := 1
+
+# METADATA
+# title: p1
+p := 2
+`)
+	d := parse(`package a
+
+# METADATA
+# title: p1
+p := 2
+
+# METADATA
+# title: p2
+p := 1
+`)
+	if act := CompareAnnotationsSemantic(c.Annotations, d.Annotations); act != 0 {
+		t.Fatalf("expected annotations with the same target to be ordered by title but got %d", act)
+	}
+
+	e := parse(`package a
+
+# METADATA
+# title: p3
+p := 1
+`)
+	if CompareAnnotationsSemantic(c.Annotations, e.Annotations) >= 0 {
+		t.Fatal("expected different titles to be compared")
+	}
+}
+
+func TestCompareWithSliceNormalized(t *testing.T) {
+	tests := []struct {
+		note string
+		a, b string
+		exp  int
+	}{
+		{"disjoint", `with input.x as 1 with input.y as 2`, `with input.y as 2 with input.x as 1`, 0},
+		{"disjoint roots", `with data.a as 1 with input as 2`, `with input as 2 with data.a as 1`, 0},
+		{"prefix", `with input as {} with input.x as 1`, `with input.x as 1 with input as {}`, -1},
+		{"same target", `with input.x as 1 with input.x as 2`, `with input.x as 2 with input.x as 1`, -1},
+		{"mixed", `with input as {} with data.a as 1 with input.x as 1`, `with data.a as 1 with input as {} with input.x as 1`, 0},
+		{"mixed reordered", `with input as {} with data.a as 1 with input.x as 1`, `with input.x as 1 with data.a as 1 with input as {}`, -1},
+		{"different values", `with input.x as 1 with input.y as 2`, `with input.y as 3 with input.x as 1`, -1},
+		{"length", `with input.x as 1`, `with input.y as 2 with input.x as 1`, -1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			a, b := MustParseExpr(`x `+tc.a).With, MustParseExpr(`x `+tc.b).With
+			if act := CompareWithSliceNormalized(a, b); act != tc.exp {
+				t.Errorf("Expected %d but got %d", tc.exp, act)
+			}
+			if act := CompareWithSliceNormalized(b, a); act != -tc.exp {
+				t.Errorf("Expected %d but got %d", -tc.exp, act)
+			}
+		})
+	}
+
+	// Two orders are equivalent if and only if all overlapping modifiers, i.e.
+	// input with input.x and input with input.y, are in the same relative
+	// order.
+	ws := MustParseExpr(`x with input as {} with input.x as 1 with data.a as 2 with input.y as 3`).With
+	index := func(order []*With, w *With) int {
+		return slices.Index(order, w)
+	}
+	key := func(order []*With) [2]bool {
+		return [2]bool{index(order, ws[0]) < index(order, ws[1]), index(order, ws[0]) < index(order, ws[3])}
+	}
+	var orders [][]*With
+	var permute func(prefix, rest []*With)
+	permute = func(prefix, rest []*With) {
+		if len(rest) == 0 {
+			orders = append(orders, prefix)
+			return
+		}
+		for i := range rest {
+			next := append(slices.Clone(prefix), rest[i])
+			permute(next, slices.Concat(rest[:i], rest[i+1:]))
+		}
+	}
+	permute(nil, ws)
+
+	for _, a := range orders {
+		for _, b := range orders {
+			if act, exp := CompareWithSliceNormalized(a, b) == 0, key(a) == key(b); act != exp {
+				t.Fatalf("Expected equivalence of %v and %v to be %v", a, b, exp)
+			}
+		}
+	}
+}
+
+func TestCompareBodyUnordered(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  int
+	}{
+		{`a == 1; b == 2`, `b == 2; a == 1`, 0},
+		{`x := input.x; not y; f(x) with input as 1`, `f(x) with input as 1; not y; x := input.x`, 0},
+		{`a == 1; b == 2`, `b == 2; a == 2`, -1},
+		{`a == 1; b == 2`, `b == 2`, -1},
+		{`[x | x = 1; y = 2]`, `[x | y = 2; x = 1]`, -1},
+	}
+	for _, tc := range tests {
+		a, b := MustParseBody(tc.a), MustParseBody(tc.b)
+		if act := CompareBodyUnordered(a, b); act != tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", tc.exp, a, b, act)
+		}
+		if act := CompareBodyUnordered(b, a); act != -tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", -tc.exp, b, a, act)
+		}
+		if a[0].Index != 0 || len(a) > 1 && a[1].Index != 1 {
+			t.Errorf("Expected %v not to be modified", a)
+		}
+	}
+}
+
+func TestCompareExprCommutative(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  int
+	}{
+		{`x == y`, `y == x`, 0},
+		{`x != y`, `y != x`, 0},
+		{`x = y`, `y = x`, 0},
+		{`z := x + y`, `z := y + x`, 0},
+		{`z := x * 2`, `z := 2 * x`, 0},
+		{`plus(x, y, z)`, `plus(y, x, z)`, 0},
+		{`plus(x, y, z)`, `plus(x, z, y)`, -1},
+		{`1 == x + y`, `y + x == 1`, 0},
+		{`s == x & y`, `y & x == s`, 0},
+		{`s == x | y`, `y | x == s`, 0},
+		{`x == y with input as 1`, `y == x with input as 1`, 0},
+		{`x < y`, `y < x`, -1},
+		{`x >= y`, `y >= x`, -1},
+		{`z := x - y`, `z := y - x`, -1},
+		{`z := x / y`, `z := y / x`, -1},
+		{`x == y`, `not y == x`, -1},
+		{`x == y`, `x == z`, -1},
+	}
+	for _, tc := range tests {
+		a, b := MustParseExpr(tc.a), MustParseExpr(tc.b)
+		sa, sb := a.String(), b.String()
+		if act := CompareExprCommutative(a, b); act != tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", tc.exp, a, b, act)
+		}
+		if act := CompareExprCommutative(b, a); act != -tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", -tc.exp, b, a, act)
+		}
+		if a.String() != sa || b.String() != sb {
+			t.Errorf("Expected %v and %v not to be modified", sa, sb)
+		}
+	}
+
+	if CompareExprCommutative(nil, MustParseExpr(`x`)) != -1 || CompareExprCommutative(nil, nil) != 0 {
+		t.Fatal("Expected nil expressions to sort first")
+	}
+}
+
+func TestCompareSomeDeclSet(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  int
+	}{
+		{`some x, y`, `some y, x`, 0},
+		{`some a, b, c`, `some c, a, b`, 0},
+		{`some x, y`, `some x, z`, -1},
+		{`some x, y`, `some x, y, z`, -1},
+		{`some x, y in c`, `some x, y in c`, 0},
+		{`some x, y in c`, `some y, x in c`, -1},
+		{`some x in c`, `some x, y in c`, -1},
+	}
+	for _, tc := range tests {
+		a := MustParseBodyWithOpts(tc.a, ParserOptions{AllFutureKeywords: true})[0].Terms.(*SomeDecl)
+		b := MustParseBodyWithOpts(tc.b, ParserOptions{AllFutureKeywords: true})[0].Terms.(*SomeDecl)
+		if act := CompareSomeDeclSet(a, b); act != tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", tc.exp, a, b, act)
+		}
+		if act := CompareSomeDeclSet(b, a); act != -tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", -tc.exp, b, a, act)
+		}
+	}
+
+	decl := MustParseBody(`some y, x`)[0].Terms.(*SomeDecl)
+	CompareSomeDeclSet(decl, decl)
+	if decl.String() != `some y, x` {
+		t.Fatalf("Expected %v not to be modified", decl)
+	}
+	if CompareSomeDeclSet(nil, decl) != -1 || CompareSomeDeclSet(decl, nil) != 1 || CompareSomeDeclSet(nil, nil) != 0 {
+		t.Fatal("Expected nil declarations to sort first")
+	}
+}
+
+func TestCompareRuleByName(t *testing.T) {
+	module := MustParseModule(`package test
+
+f(x, y) := x + y if x > 0
+g := 1
+f(x) := x if x > 0
+f(1) := 2
+default f(_) := 0
+f(x) := 2 * x if x < 0
+a.b := 1
+default g := 0
+`)
+	rules := slices.Clone(module.Rules)
+	slices.SortStableFunc(rules, CompareRuleByName)
+
+	exp := []string{
+		`a.b := 1 if { true }`,
+		`f(1) := 2 if { true }`,
+		`default f(_) := 0`,
+		`f(x) := x if { gt(x, 0) }`,
+		`f(x) := mul(2, x) if { lt(x, 0) }`,
+		`f(x, y) := plus(x, y) if { gt(x, 0) }`,
+		`default g := 0`,
+		`g := 1 if { true }`,
+	}
+	act := make([]string, len(rules))
+	for i, rule := range rules {
+		act[i] = rule.String()
+	}
+	if !slices.Equal(exp, act) {
+		t.Fatalf("Expected:\n%v\nGot:\n%v", strings.Join(exp, "\n"), strings.Join(act, "\n"))
+	}
+
+	if CompareRuleByName(rules[3], rules[4]) != 0 {
+		t.Fatal("Expected rules that only differ in their bodies to be equal")
+	}
+	if CompareRuleByName(nil, rules[0]) >= 0 {
+		t.Fatal("Expected nil rule to sort first")
+	}
+}
+
+func TestComparePackagePath(t *testing.T) {
+	paths := []string{`a.c`, `a.b.x`, `b`, `a`, `a.b`, `a.b.x.y`, `a["b-c"]`, `a.b.y`}
+	exp := []string{`a`, `a.b`, `a.b.x`, `a.b.x.y`, `a.b.y`, `a["b-c"]`, `a.c`, `b`}
+
+	pkgs := make([]*Package, len(paths))
+	for i, p := range paths {
+		pkgs[i] = MustParsePackage(`package ` + p)
+	}
+
+	for _, compare := range []func(a, b *Package) int{ComparePackagePath, (*Package).Compare} {
+		sorted := slices.Clone(pkgs)
+		slices.SortFunc(sorted, compare)
+		act := make([]string, len(sorted))
+		for i, pkg := range sorted {
+			act[i] = strings.TrimPrefix(pkg.String(), "package ")
+		}
+		if !slices.Equal(act, exp) {
+			t.Fatalf("Expected %v but got %v", exp, act)
+		}
+	}
+
+	// Heads are compared by name.
+	a := MustParsePackage(`package a.b`)
+	b := &Package{Path: Ref{StringTerm("data"), StringTerm("a"), StringTerm("b")}}
+	if act := ComparePackagePath(a, b); act != 0 {
+		t.Fatalf("Expected %v and %v to be equal but got %d", a, b, act)
+	}
+
+	if ComparePackagePath(nil, a) != -1 || ComparePackagePath(a, nil) != 1 || ComparePackagePath(nil, nil) != 0 {
+		t.Fatal("Expected nil packages to sort first")
+	}
+}
+
+func TestCompareHeadKind(t *testing.T) {
+	module := MustParseModule(`package test
+
+p := 1
+p contains 1
+p[x] := 1 if x := "a"
+f(x) := 1
+q := 2
+q contains 2
+q.r[x] := 2 if x := "b"
+g(x, y) := 2
+`)
+	// The rules for q have the same kinds as those for p, in the same order.
+	heads := make([]*Head, len(module.Rules))
+	for i, rule := range module.Rules {
+		heads[i] = rule.Head
+	}
+
+	for i := range 4 {
+		for j := range 4 {
+			a, b := heads[i], heads[4+j]
+			act, kindDiff := CompareHeadKind(a, b)
+			if kindDiff != (i != j) {
+				t.Errorf("Expected kindDiff for %v and %v to be %v", a, b, i != j)
+			}
+			exp := cmp.Compare(i, j)
+			if i == j {
+				exp = a.Compare(b)
+			}
+			if act != exp {
+				t.Errorf("Expected CompareHeadKind(%v, %v) == %d but got %d", a, b, exp, act)
+			}
+		}
+	}
+
+	if act, kindDiff := CompareHeadKind(heads[0], heads[0]); act != 0 || kindDiff {
+		t.Errorf("Expected equal heads to compare as (0, false) but got (%d, %v)", act, kindDiff)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"cmp"
+	"strings"
+)
+
+// RefCompareNormalized compares a and b like RefCompare, except that a head
+// given as a String is treated like a Var with the same name. Two refs are
+// thus equivalent if their heads have the same name, whether they are Vars or
+// Strings, and all other terms are equal. For example, data.foo.bar is
+// equivalent to a ref constructed as Ref{StringTerm("data"),
+// StringTerm("foo"), StringTerm("bar")}. Refs whose heads are neither Vars nor
+// Strings are compared like RefCompare.
+func RefCompareNormalized(a, b Ref) int {
+	if ha, ok := refHeadName(a); ok {
+		if hb, ok := refHeadName(b); ok {
+			if cmp := strings.Compare(ha, hb); cmp != 0 {
+				return cmp
+			}
+			return termSliceCompare(a[1:], b[1:])
+		}
+	}
+	return termSliceCompare(a, b)
+}
+
+// RefEqualNormalized returns true if a and b are equivalent as defined by
+// RefCompareNormalized.
+func RefEqualNormalized(a, b Ref) bool {
+	if ha, ok := refHeadName(a); ok {
+		if hb, ok := refHeadName(b); ok {
+			return ha == hb && termSliceEqual(a[1:], b[1:])
+		}
+	}
+	return termSliceEqual(a, b)
+}
+
+// ComparePrefix compares a and b element by element like Compare and also
+// reports whether the shorter of the two is a proper prefix of the longer. If
+// it is, the shorter ref is less. Equal refs compare as 0 and are not proper
+// prefixes of each other.
+func ComparePrefix(a, b Ref) (int, bool) {
+	for i := range min(len(a), len(b)) {
+		if c := compare(a[i], b[i]); c != 0 {
+			return c, false
+		}
+	}
+	return cmp.Compare(len(a), len(b)), len(a) != len(b)
+}
+
+// RefCommonPrefix returns the longest prefix that a and b have in common, i.e.
+// the leading terms of a for which ValueEqual holds with the terms of b at the
+// same positions. The result is empty if the heads of a and b differ. It
+// shares its underlying array with a, but appending to it does not modify a.
+func RefCommonPrefix(a, b Ref) Ref {
+	n := 0
+	for n < min(len(a), len(b)) && ValueEqual(a[n].Value, b[n].Value) {
+		n++
+	}
+	return a[:n:n]
+}
+
+func refHeadName(ref Ref) (string, bool) {
+	if len(ref) == 0 || ref[0] == nil {
+		return "", false
+	}
+	switch v := ref[0].Value.(type) {
+	case Var:
+		return string(v), true
+	case String:
+		return string(v), true
+	}
+	return "", false
+}
@@ -0,0 +1,103 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "testing"
+
+func TestComparePrefix(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		cmp      int
+		isPrefix bool
+	}{
+		{"data.a", "data.a.b", -1, true},
+		{"data.a.b", "data.a", 1, true},
+		{"data.a", "data.a", 0, false},
+		{"data.a", "data.b", -1, false},
+		{"data.a.b", "data.b", -1, false},
+		{"data.b", "data.a.b", 1, false},
+		{"data", "data.a[x]", -1, true},
+	}
+	for _, tc := range tests {
+		cmp, isPrefix := ComparePrefix(MustParseRef(tc.a), MustParseRef(tc.b))
+		if cmp != tc.cmp || isPrefix != tc.isPrefix {
+			t.Errorf("expected ComparePrefix(%v, %v) == (%d, %v) but got (%d, %v)", tc.a, tc.b, tc.cmp, tc.isPrefix, cmp, isPrefix)
+		}
+	}
+}
+
+func TestRefCommonPrefix(t *testing.T) {
+	tests := []struct {
+		a, b, exp string
+	}{
+		{"data.a.b.c", "data.a.b.d", "data.a.b"},
+		{"data.a.b", "data.a.b", "data.a.b"},
+		{"data.a", "data.a.b", "data.a"},
+		{"data.x", "input.x", ""},
+		{"data.a[x].b", "data.a[x].c", "data.a[x]"},
+		{"data.a[x]", "data.a[y]", "data.a"},
+		{"data.a[1]", "data.a[1.0]", "data.a[1]"},
+	}
+	for _, tc := range tests {
+		act := RefCommonPrefix(MustParseRef(tc.a), MustParseRef(tc.b))
+		var exp Ref
+		if tc.exp != "" {
+			exp = MustParseRef(tc.exp)
+		}
+		if len(act) != len(exp) || !act.Equal(exp) {
+			t.Errorf("Expected common prefix of %v and %v to be %v but got %v", tc.a, tc.b, exp, act)
+		}
+	}
+
+	// Appending to the prefix must not modify a.
+	a := MustParseRef("data.a.b")
+	_ = append(RefCommonPrefix(a, MustParseRef("data.a.c")), StringTerm("x"))
+	if !a.Equal(MustParseRef("data.a.b")) {
+		t.Fatalf("Expected ref not to be modified but got %v", a)
+	}
+}
+
+func TestRefCompareNormalized(t *testing.T) {
+	parsed := MustParseRef("data.foo.bar")
+	constructed := Ref{StringTerm("data"), StringTerm("foo"), StringTerm("bar")}
+
+	if Compare(parsed, constructed) == 0 {
+		t.Fatal("expected Compare to distinguish var and string heads")
+	}
+	if RefCompareNormalized(parsed, constructed) != 0 || RefCompareNormalized(constructed, parsed) != 0 {
+		t.Fatalf("expected %v and %v to be equivalent", parsed, constructed)
+	}
+	if RefCompare(parsed, constructed) == 0 || RefEqual(parsed, constructed) {
+		t.Fatal("expected RefCompare and RefEqual to distinguish var and string heads")
+	}
+	if !RefEqualNormalized(parsed, constructed) {
+		t.Fatalf("expected %v and %v to be equal", parsed, constructed)
+	}
+
+	tests := []struct {
+		a, b Ref
+		exp  int
+	}{
+		{MustParseRef("data.foo"), Ref{StringTerm("input"), StringTerm("foo")}, -1},
+		{Ref{StringTerm("data"), StringTerm("foo")}, MustParseRef("data.foo.bar"), -1},
+		{MustParseRef("data.foo[x]"), Ref{StringTerm("data"), StringTerm("foo"), VarTerm("x")}, 0},
+		{MustParseRef("data.foo[x]"), Ref{StringTerm("data"), StringTerm("foo"), StringTerm("x")}, 1},
+		{Ref{NumberTerm("1"), StringTerm("foo")}, MustParseRef("data.foo"), -1},
+		{MustParseRef("data.foo"), Ref{ArrayTerm(), StringTerm("foo")}, -1},
+		{Ref{}, MustParseRef("data"), -1},
+	}
+
+	for _, tc := range tests {
+		if result := RefCompareNormalized(tc.a, tc.b); result != tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", tc.exp, tc.a, tc.b, result)
+		}
+		if result := RefCompareNormalized(tc.b, tc.a); result != -tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", -tc.exp, tc.b, tc.a, result)
+		}
+		if RefEqualNormalized(tc.a, tc.b) != (tc.exp == 0) {
+			t.Errorf("expected RefEqualNormalized to agree with RefCompareNormalized for %v and %v", tc.a, tc.b)
+		}
+	}
+}
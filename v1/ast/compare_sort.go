@@ -0,0 +1,228 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// SortTerms sorts terms in place according to Compare. Nil terms sort first.
+func SortTerms(terms []*Term) {
+	sort.Sort(termSlice(terms))
+}
+
+// SortTermsStable is like SortTerms but keeps terms that compare equal in
+// their original relative order.
+func SortTermsStable(terms []*Term) {
+	sort.Stable(termSlice(terms))
+}
+
+// DedupTerms sorts terms by Compare and removes duplicates, i.e. terms with
+// equal values as reported by ValueEqual, so 1 and 1.0 are duplicates. Of
+// several equal terms, the one that came first in terms is kept. Like
+// slices.Compact, DedupTerms modifies terms in place and returns the
+// shortened slice. Nil terms are sorted first and deduplicated as well.
+func DedupTerms(terms []*Term) []*Term {
+	SortTermsStable(terms)
+	return slices.CompactFunc(terms, func(a, b *Term) bool {
+		return compare(a, b) == 0
+	})
+}
+
+// IsSortedTerms reports whether terms are sorted according to Compare, as
+// done by SortTerms. Nil terms must come first, and equal terms may be
+// adjacent in any order.
+func IsSortedTerms(terms []*Term) bool {
+	return slices.IsSortedFunc(terms, termCompare)
+}
+
+// IsSortedTermsFunc reports whether terms are sorted according to f, e.g. one
+// of the comparison functions of this package such as
+// CompareBySourceLocation.
+func IsSortedTermsFunc(terms []*Term, f func(a, b *Term) int) bool {
+	return slices.IsSortedFunc(terms, f)
+}
+
+// SearchTerms searches for target in terms, which must be sorted according to
+// Compare, and returns the index of the first term equal to target and true,
+// or the index at which target would be inserted and false. A nil target
+// matches nil terms.
+func SearchTerms(terms []*Term, target Value) (int, bool) {
+	return slices.BinarySearchFunc(terms, target, func(t *Term, target Value) int {
+		return compare(t, target)
+	})
+}
+
+// Bucketize counts how many of values fall into each of the half-open
+// intervals defined by boundaries, which must be sorted according to Compare.
+// The result has one more bucket than there are boundaries: bucket 0 counts
+// the values less than boundaries[0], bucket i the values v with
+// boundaries[i-1] <= v < boundaries[i], and the last bucket the values
+// greater than or equal to the last boundary. Values are compared like
+// Compare, so they need not be numbers. Bucketize panics if boundaries are
+// not sorted.
+func Bucketize(values, boundaries []*Term) []int {
+	if !IsSortedTerms(boundaries) {
+		panic("illegal boundaries: not sorted")
+	}
+	counts := make([]int, len(boundaries)+1)
+	for _, v := range values {
+		var target Value
+		if v != nil {
+			target = v.Value
+		}
+		i, found := SearchTerms(boundaries, target)
+		if found {
+			// Skip to the bucket following all boundaries equal to v.
+			for i < len(boundaries) && compare(boundaries[i], target) == 0 {
+				i++
+			}
+		}
+		counts[i]++
+	}
+	return counts
+}
+
+// SortTermsByKey sorts terms in place by Compare of the keys that key returns
+// for them, e.g. the value of a field of objects. Terms with equal keys keep
+// their original relative order, and terms for which key returns nil sort
+// first. key is called once per term.
+func SortTermsByKey(terms []*Term, key func(*Term) *Term) {
+	keyed := make([]struct{ key, term *Term }, len(terms))
+	for i, t := range terms {
+		keyed[i].key, keyed[i].term = key(t), t
+	}
+	slices.SortStableFunc(keyed, func(a, b struct{ key, term *Term }) int {
+		return compare(a.key, b.key)
+	})
+	for i := range keyed {
+		terms[i] = keyed[i].term
+	}
+}
+
+// MergeSortedTerms returns the sorted union of a and b, which must both be
+// sorted according to Compare. Terms that are equal are only included once,
+// even if a or b contains duplicates, and the first one encountered is kept.
+// Unlike sorting the concatenation of a and b, this takes linear time.
+func MergeSortedTerms(a, b []*Term) []*Term {
+	result := make([]*Term, 0, len(a)+len(b))
+	appendTerm := func(t *Term) {
+		if len(result) == 0 || compare(result[len(result)-1], t) != 0 {
+			result = append(result, t)
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if compare(a[i], b[j]) <= 0 {
+			appendTerm(a[i])
+			i++
+		} else {
+			appendTerm(b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		appendTerm(a[i])
+	}
+	for ; j < len(b); j++ {
+		appendTerm(b[j])
+	}
+	return result
+}
+
+// MinTerm returns the least of terms according to Compare, or nil if terms is
+// empty. If several terms are equal to the minimum, the first one is returned.
+// Since Compare orders values of different types by type, the minimum of
+// heterogeneous terms is well-defined, e.g. null is less than any other value.
+func MinTerm(terms []*Term) *Term {
+	var result *Term
+	for i, t := range terms {
+		if i == 0 || Compare(t, result) < 0 {
+			result = t
+		}
+	}
+	return result
+}
+
+// MaxTerm returns the greatest of terms according to Compare, or nil if terms
+// is empty. If several terms are equal to the maximum, the first one is
+// returned.
+func MaxTerm(terms []*Term) *Term {
+	var result *Term
+	for i, t := range terms {
+		if i == 0 || Compare(t, result) > 0 {
+			result = t
+		}
+	}
+	return result
+}
+
+// ValueClamp returns lo if v is less than lo, hi if v is greater than hi, and
+// v otherwise, according to Compare. Since Compare orders values of different
+// types by type, v does not need to have the same type as lo and hi, e.g.
+// clamping any string to the range [1, 10] returns 10. If v is equal to lo or
+// hi, v is returned, so clamping 1.0 to [1, 2] returns 1.0.
+//
+// ValueClamp panics if lo is greater than hi, as there is no sensible result
+// and such a range is most likely a mistake of the caller.
+func ValueClamp(v, lo, hi Value) Value {
+	if Compare(lo, hi) > 0 {
+		panic(fmt.Sprintf("illegal range: lo %v is greater than hi %v", lo, hi))
+	}
+	if Compare(v, lo) < 0 {
+		return lo
+	}
+	if Compare(v, hi) > 0 {
+		return hi
+	}
+	return v
+}
+
+// TermItem wraps a term for ordered collections that order their items with a
+// Less method, e.g. B-trees. Items are ordered like their terms by Compare,
+// with nil terms first. The method expression TermItem.Less can be used where
+// a less function is expected instead.
+type TermItem struct {
+	Term *Term
+}
+
+// Less reports whether i sorts before than according to Compare.
+func (i TermItem) Less(than TermItem) bool {
+	return compare(i.Term, than.Term) < 0
+}
+
+// CompareBySourceLocation orders a and b by their locations, i.e. by file name,
+// row and column, and falls back to Compare if their locations are equal or
+// both missing. Terms without a location sort after terms with one. This can
+// be used to restore source order after a transformation.
+func CompareBySourceLocation(a, b *Term) int {
+	var aloc, bloc *Location
+	if a != nil {
+		aloc = a.Location
+	}
+	if b != nil {
+		bloc = b.Location
+	}
+	if c := aloc.Compare(bloc); c != 0 {
+		return c
+	}
+	return Compare(a, b)
+}
+
+// CompareReverse is like Compare, but orders values in descending order. It
+// swaps a and b rather than negating the result of Compare.
+func CompareReverse(a, b any) int {
+	return Compare(b, a)
+}
+
+// DescendingTerms implements sort.Interface to sort terms in descending order
+// according to Compare, e.g. sort.Sort(DescendingTerms(terms)).
+type DescendingTerms []*Term
+
+func (s DescendingTerms) Less(i, j int) bool { return Compare(s[j], s[i]) < 0 }
+func (s DescendingTerms) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s DescendingTerms) Len() int           { return len(s) }
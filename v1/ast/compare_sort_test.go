@@ -0,0 +1,518 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestSortTerms(t *testing.T) {
+	terms := []*Term{
+		StringTerm("a"),
+		NumberTerm("2"),
+		nil,
+		NullTerm(),
+		BooleanTerm(true),
+		NumberTerm("1"),
+		ArrayTerm(),
+	}
+	exp := []*Term{nil, NullTerm(), BooleanTerm(true), NumberTerm("1"), NumberTerm("2"), StringTerm("a"), ArrayTerm()}
+
+	SortTerms(terms)
+
+	for i := range exp {
+		if Compare(terms[i], exp[i]) != 0 {
+			t.Fatalf("Expected %v but got %v", exp, terms)
+		}
+	}
+}
+
+func TestSortTermsStable(t *testing.T) {
+	one, oneDotZero, oneE0 := NumberTerm("1"), NumberTerm("1.0"), NumberTerm("1e0")
+	terms := []*Term{StringTerm("x"), oneDotZero, NumberTerm("0"), one, oneE0}
+
+	SortTermsStable(terms)
+
+	exp := []*Term{NumberTerm("0"), oneDotZero, one, oneE0, StringTerm("x")}
+	for i := range exp {
+		if Compare(terms[i], exp[i]) != 0 {
+			t.Fatalf("Expected %v but got %v", exp, terms)
+		}
+	}
+	if terms[1] != oneDotZero || terms[2] != one || terms[3] != oneE0 {
+		t.Fatalf("Expected equal terms to keep their relative order but got %v", terms)
+	}
+}
+
+func TestDedupTerms(t *testing.T) {
+	one := IntNumberTerm(1)
+	obj := MustParseTerm(`{"a": [1, {2}], "b": null}`)
+	terms := []*Term{
+		StringTerm("x"),
+		NumberTerm("1.0"),
+		obj,
+		one,
+		MustParseTerm(`{"b": null, "a": [1.0, {2.0}]}`),
+		NumberTerm("1e0"),
+		nil,
+		StringTerm("x"),
+		NumberTerm("0.1e1"),
+		nil,
+		MustParseTerm(`{"a": [1, {2}], "b": false}`),
+	}
+	first := terms[1]
+
+	result := DedupTerms(terms)
+	exp := []string{`<nil>`, `1.0`, `"x"`, `{"a": [1, {2}], "b": null}`, `{"a": [1, {2}], "b": false}`}
+	act := make([]string, len(result))
+	for i, term := range result {
+		if term == nil {
+			act[i] = "<nil>"
+		} else {
+			act[i] = term.String()
+		}
+	}
+	if !slices.Equal(act, exp) {
+		t.Fatalf("Expected %v but got %v", exp, act)
+	}
+	if result[1] != first || result[3] != obj {
+		t.Fatal("Expected the first of several equal terms to be kept")
+	}
+	if !IsSortedTerms(result) {
+		t.Fatalf("Expected %v to be sorted", result)
+	}
+
+	if result := DedupTerms(nil); len(result) != 0 {
+		t.Fatalf("Expected empty result but got %v", result)
+	}
+}
+
+func TestIsSortedTerms(t *testing.T) {
+	tests := []struct {
+		note   string
+		terms  []*Term
+		sorted bool
+	}{
+		{"empty", nil, true},
+		{"single", []*Term{StringTerm("a")}, true},
+		{"sorted", []*Term{NullTerm(), IntNumberTerm(1), NumberTerm("1.5"), StringTerm("a"), ArrayTerm()}, true},
+		{"equal", []*Term{IntNumberTerm(1), NumberTerm("1.0"), NumberTerm("1e0")}, true},
+		{"nil first", []*Term{nil, nil, NullTerm(), BooleanTerm(true)}, true},
+		{"unsorted", []*Term{IntNumberTerm(1), StringTerm("a"), NumberTerm("1.5")}, false},
+		{"descending", []*Term{StringTerm("b"), StringTerm("a")}, false},
+		{"nil last", []*Term{NullTerm(), nil}, false},
+		{"nil between", []*Term{nil, NullTerm(), nil}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			if act := IsSortedTerms(tc.terms); act != tc.sorted {
+				t.Errorf("Expected IsSortedTerms(%v) to be %v", tc.terms, tc.sorted)
+			}
+			if act := IsSortedTermsFunc(tc.terms, TermCompareFunc()); act != tc.sorted {
+				t.Errorf("Expected IsSortedTermsFunc(%v) to be %v", tc.terms, tc.sorted)
+			}
+		})
+	}
+
+	terms := []*Term{StringTerm("a"), StringTerm("b"), StringTerm("c")}
+	if IsSortedTermsFunc(terms, func(a, b *Term) int { return Compare(b, a) }) {
+		t.Fatalf("Expected %v not to be sorted in descending order", terms)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for range 100 {
+		terms := make([]*Term, rng.Intn(20))
+		for i := range terms {
+			terms[i] = NewTerm(GenRandomValue(rng, 2))
+		}
+		SortTerms(terms)
+		if !IsSortedTerms(terms) {
+			t.Fatalf("Expected %v to be sorted", terms)
+		}
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	terms := []*Term{
+		nil,
+		NullTerm(),
+		IntNumberTerm(1),
+		NumberTerm("1.0"),
+		NumberTerm("1e0"),
+		IntNumberTerm(3),
+		StringTerm("a"),
+		StringTerm("c"),
+		ArrayTerm(IntNumberTerm(1)),
+	}
+
+	tests := []struct {
+		target Value
+		index  int
+		found  bool
+	}{
+		{nil, 0, true},
+		{Null{}, 1, true},
+		{Boolean(false), 2, false},
+		{Number("1"), 2, true},
+		{Number("1.00"), 2, true},
+		{Number("2"), 5, false},
+		{Number("-1"), 2, false},
+		{Number("3"), 5, true},
+		{String(""), 6, false},
+		{String("a"), 6, true},
+		{String("b"), 7, false},
+		{String("c"), 7, true},
+		{NewArray(), 8, false},
+		{NewArray(IntNumberTerm(1)), 8, true},
+		{NewArray(IntNumberTerm(2)), 9, false},
+		{NewObject(), 9, false},
+	}
+
+	for _, tc := range tests {
+		index, found := SearchTerms(terms, tc.target)
+		if index != tc.index || found != tc.found {
+			t.Errorf("Expected (%d, %v) for %v but got (%d, %v)", tc.index, tc.found, tc.target, index, found)
+		}
+	}
+
+	if index, found := SearchTerms(nil, String("a")); index != 0 || found {
+		t.Errorf("Expected (0, false) for empty slice but got (%d, %v)", index, found)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for range 100 {
+		terms := make([]*Term, rng.Intn(20))
+		for i := range terms {
+			terms[i] = NewTerm(GenRandomValue(rng, 1))
+		}
+		SortTerms(terms)
+		target := GenRandomValue(rng, 1)
+		index, found := SearchTerms(terms, target)
+		exp := slices.IndexFunc(terms, func(t *Term) bool { return Compare(t, target) >= 0 })
+		if exp < 0 {
+			exp = len(terms)
+		}
+		expFound := exp < len(terms) && Compare(terms[exp], target) == 0
+		if index != exp || found != expFound {
+			t.Fatalf("Expected (%d, %v) for %v in %v but got (%d, %v)", exp, expFound, target, terms, index, found)
+		}
+	}
+}
+
+// termItemHeap is an ordered collection that relies on TermItem.Less.
+type termItemHeap []TermItem
+
+func (h termItemHeap) Len() int           { return len(h) }
+func (h termItemHeap) Less(i, j int) bool { return h[i].Less(h[j]) }
+func (h termItemHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *termItemHeap) Push(x any)        { *h = append(*h, x.(TermItem)) }
+func (h *termItemHeap) Pop() any {
+	x := (*h)[len(*h)-1]
+	*h = (*h)[:len(*h)-1]
+	return x
+}
+
+func TestTermItem(t *testing.T) {
+	terms := []*Term{
+		MustParseTerm(`{"a": 1}`),
+		StringTerm("b"),
+		IntNumberTerm(2),
+		nil,
+		MustParseTerm(`[1, 2]`),
+		NullTerm(),
+		MustParseTerm(`{1, 2}`),
+		NumberTerm("1.5"),
+		BooleanTerm(true),
+		StringTerm("a"),
+		VarTerm("x"),
+		MustParseTerm(`data.a`),
+		BooleanTerm(false),
+	}
+
+	h := &termItemHeap{}
+	var sorted []TermItem
+	for _, term := range terms {
+		heap.Push(h, TermItem{Term: term})
+
+		// Insert in order, as an ordered map would.
+		item := TermItem{Term: term}
+		i := sort.Search(len(sorted), func(i int) bool { return item.Less(sorted[i]) })
+		sorted = slices.Insert(sorted, i, item)
+	}
+
+	exp := slices.Clone(terms)
+	SortTerms(exp)
+	for i := range exp {
+		item := heap.Pop(h).(TermItem)
+		if Compare(item.Term, exp[i]) != 0 {
+			t.Fatalf("Expected %v at position %d of heap but got %v", exp[i], i, item.Term)
+		}
+		if Compare(sorted[i].Term, exp[i]) != 0 {
+			t.Fatalf("Expected %v at position %d of sorted items but got %v", exp[i], i, sorted[i].Term)
+		}
+	}
+
+	less := TermItem.Less
+	if !less(TermItem{IntNumberTerm(1)}, TermItem{StringTerm("1")}) || less(TermItem{IntNumberTerm(1)}, TermItem{NumberTerm("1.0")}) {
+		t.Fatal("Expected TermItem.Less to agree with Compare")
+	}
+}
+
+func TestBucketize(t *testing.T) {
+	terms := func(s string) []*Term {
+		if s == "" {
+			return nil
+		}
+		return MustParseTerm("[" + s + "]").Value.(*Array).elems
+	}
+
+	tests := []struct {
+		note       string
+		values     string
+		boundaries string
+		exp        []int
+	}{
+		{"empty", ``, `0, 10`, []int{0, 0, 0}},
+		{"no boundaries", `1, 2, 3`, ``, []int{3}},
+		{"interior", `1, 5, 9.5`, `0, 10`, []int{0, 3, 0}},
+		{"on boundaries", `0, 10, 20`, `0, 10, 20`, []int{0, 1, 1, 1}},
+		{"overflow", `-1, -0.5, 20, 100`, `0, 10, 20`, []int{2, 0, 0, 2}},
+		{"spelling", `1.0, 1e1, 0.99`, `1, 10`, []int{1, 1, 1}},
+		{"duplicate boundaries", `0, 1, 1, 2`, `1, 1, 2`, []int{1, 0, 2, 1}},
+		{"types", `null, "a", 5, [1]`, `1, "b"`, []int{1, 2, 1}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			if act := Bucketize(terms(tc.values), terms(tc.boundaries)); !slices.Equal(act, tc.exp) {
+				t.Fatalf("Expected %v but got %v", tc.exp, act)
+			}
+		})
+	}
+
+	if act := Bucketize([]*Term{nil, NullTerm()}, []*Term{nil, NullTerm()}); !slices.Equal(act, []int{0, 1, 1}) {
+		t.Fatalf("Expected nil values to be bucketed like nil boundaries but got %v", act)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected panic for unsorted boundaries")
+		}
+	}()
+	Bucketize(terms(`1`), terms(`10, 0`))
+}
+
+func TestSortTermsByKey(t *testing.T) {
+	terms := MustParseTerm(`[
+		{"name": "a", "meta": {"priority": 2}},
+		{"name": "b", "meta": {"priority": 1.0}},
+		{"name": "c"},
+		{"name": "d", "meta": {"priority": 1}},
+		{"name": "e", "meta": {"priority": "high"}},
+		{"name": "f", "meta": {}}
+	]`).Value.(*Array).elems
+
+	calls := 0
+	SortTermsByKey(terms, func(t *Term) *Term {
+		calls++
+		if meta := t.Get(StringTerm("meta")); meta != nil {
+			return meta.Get(StringTerm("priority"))
+		}
+		return nil
+	})
+
+	act := make([]string, len(terms))
+	for i, term := range terms {
+		act[i] = string(term.Get(StringTerm("name")).Value.(String))
+	}
+	// Terms without a priority sort first, in their original order, and so do
+	// terms with equal priorities.
+	if exp := []string{"c", "f", "b", "d", "a", "e"}; !slices.Equal(act, exp) {
+		t.Fatalf("Expected %v but got %v", exp, act)
+	}
+	if calls != len(terms) {
+		t.Fatalf("Expected key to be called %d times but got %d", len(terms), calls)
+	}
+}
+
+func TestValueClamp(t *testing.T) {
+	tests := []struct {
+		v, lo, hi string
+		exp       string
+	}{
+		{`5`, `1`, `10`, `5`},
+		{`0`, `1`, `10`, `1`},
+		{`-1.5`, `-1`, `1`, `-1`},
+		{`11`, `1`, `10`, `10`},
+		{`1e3`, `1`, `10`, `10`},
+		{`1.0`, `1`, `2`, `1.0`},
+		{`3`, `3`, `3`, `3`},
+		{`"m"`, `"c"`, `"x"`, `"m"`},
+		{`"a"`, `"c"`, `"x"`, `"c"`},
+		{`"z"`, `"c"`, `"x"`, `"x"`},
+		{`"xa"`, `"c"`, `"x"`, `"x"`},
+		{`"abc"`, `1`, `10`, `10`},
+		{`null`, `1`, `10`, `1`},
+		{`[1]`, `0`, `"z"`, `"z"`},
+		{`2`, `1`, `"z"`, `2`},
+	}
+
+	for _, tc := range tests {
+		v, lo, hi := MustParseTerm(tc.v).Value, MustParseTerm(tc.lo).Value, MustParseTerm(tc.hi).Value
+		if act := ValueClamp(v, lo, hi); act.String() != tc.exp {
+			t.Errorf("Expected clamping %v to [%v, %v] to return %v but got %v", v, lo, hi, tc.exp, act)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected panic for lo greater than hi")
+		}
+	}()
+	ValueClamp(Number("1"), Number("2"), Number("1"))
+}
+
+func TestMinMaxTerm(t *testing.T) {
+	tests := []struct {
+		terms    string
+		min, max string
+	}{
+		{`[true, 1, "a", null]`, `null`, `"a"`},
+		{`[3, 1.5, 2]`, `1.5`, `3`},
+		{`[{"a": 1}, [1], {1}, x]`, `x`, `{1}`},
+		{`[1]`, `1`, `1`},
+	}
+
+	for _, tc := range tests {
+		terms := MustParseTerm(tc.terms).Value.(*Array).elems
+		if result := MinTerm(terms); !result.Equal(MustParseTerm(tc.min)) {
+			t.Errorf("expected min of %v to be %v but got %v", tc.terms, tc.min, result)
+		}
+		if result := MaxTerm(terms); !result.Equal(MustParseTerm(tc.max)) {
+			t.Errorf("expected max of %v to be %v but got %v", tc.terms, tc.max, result)
+		}
+	}
+
+	if MinTerm(nil) != nil || MaxTerm([]*Term{}) != nil {
+		t.Fatal("expected nil for empty input")
+	}
+
+	// The first of several equal terms is returned.
+	one, onePointZero := IntNumberTerm(1), NumberTerm("1.0")
+	if MinTerm([]*Term{one, onePointZero}) != one || MaxTerm([]*Term{one, onePointZero}) != one {
+		t.Fatal("expected first of equal terms")
+	}
+}
+
+func TestCompareBySourceLocation(t *testing.T) {
+	at := func(v Value, file string, row, col int) *Term {
+		return &Term{Value: v, Location: NewLocation(nil, file, row, col)}
+	}
+	terms := []*Term{
+		IntNumberTerm(0),
+		at(String("c"), "b.rego", 1, 1),
+		at(String("b"), "a.rego", 2, 1),
+		StringTerm("a"),
+		at(String("a"), "a.rego", 1, 5),
+		nil,
+		at(String("z"), "a.rego", 1, 1),
+		at(String("y"), "a.rego", 1, 1),
+	}
+	slices.SortFunc(terms, CompareBySourceLocation)
+
+	exp := []string{
+		`"y"@a.rego:1:1`,
+		`"z"@a.rego:1:1`,
+		`"a"@a.rego:1:5`,
+		`"b"@a.rego:2:1`,
+		`"c"@b.rego:1:1`,
+		`<nil>`,
+		`0`,
+		`"a"`,
+	}
+	act := make([]string, len(terms))
+	for i, term := range terms {
+		switch {
+		case term == nil:
+			act[i] = "<nil>"
+		case term.Location == nil:
+			act[i] = term.String()
+		default:
+			act[i] = fmt.Sprintf("%v@%s:%d:%d", term, term.Location.File, term.Location.Row, term.Location.Col)
+		}
+	}
+	if !slices.Equal(exp, act) {
+		t.Fatalf("expected %v but got %v", exp, act)
+	}
+}
+
+func TestMergeSortedTerms(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomSorted := func() []*Term {
+		terms := make([]*Term, rng.Intn(8))
+		for i := range terms {
+			// Small values make duplicates within and across slices likely.
+			terms[i] = NewTerm(respell(rng, GenRandomValue(rng, 1)))
+		}
+		slices.SortFunc(terms, func(x, y *Term) int { return Compare(x, y) })
+		return terms
+	}
+
+	for range 1000 {
+		a, b := randomSorted(), randomSorted()
+		exp := slices.SortedFunc(slices.Values(slices.Concat(a, b)), func(x, y *Term) int { return Compare(x, y) })
+		exp = slices.CompactFunc(exp, func(x, y *Term) bool { return ValueEqual(x.Value, y.Value) })
+
+		act := MergeSortedTerms(a, b)
+		if !slices.EqualFunc(exp, act, func(x, y *Term) bool { return ValueEqual(x.Value, y.Value) }) {
+			t.Fatalf("expected merge of %v and %v to be %v but got %v", a, b, exp, act)
+		}
+	}
+
+	a := []*Term{IntNumberTerm(1), IntNumberTerm(1), IntNumberTerm(2)}
+	if act := MergeSortedTerms(a, nil); len(act) != 2 {
+		t.Fatalf("expected duplicates to be removed but got %v", act)
+	}
+	if act := MergeSortedTerms(nil, nil); len(act) != 0 {
+		t.Fatalf("expected empty result but got %v", act)
+	}
+}
+
+func TestCompareReverse(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{`1`, `2`},
+		{`1`, `1.0`},
+		{`"a"`, `null`},
+		{`[1, 2]`, `[1]`},
+		{`{"a": 1}`, `{"a": 2}`},
+	}
+
+	for _, tc := range tests {
+		a, b := MustParseTerm(tc.a), MustParseTerm(tc.b)
+		if result := CompareReverse(a, b); result != Compare(b, a) {
+			t.Errorf("expected %d for %v and %v but got %d", Compare(b, a), a, b, result)
+		}
+	}
+}
+
+func TestDescendingTerms(t *testing.T) {
+	terms := MustParseTerm(`[2, "a", null, 1.0, [1], 10, false, 1]`).Value.(*Array).elems
+	sort.Stable(DescendingTerms(terms))
+
+	exp := MustParseTerm(`[[1], "a", 10, 2, 1.0, 1, false, null]`).Value.(*Array).elems
+	for i := range exp {
+		if terms[i].String() != exp[i].String() {
+			t.Fatalf("expected %v but got %v", exp, terms)
+		}
+	}
+}
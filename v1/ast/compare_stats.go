@@ -0,0 +1,53 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// compareStatsEnabled guards all other compare stats state. It is loaded for
+// every pair of values compared, which is as cheap as reading a plain bool on
+// most platforms, so comparisons pay next to nothing while stats are disabled.
+var compareStatsEnabled atomic.Bool
+
+// compareStats maps [2]int pairs of sort orders to *atomic.Uint64 counters.
+var compareStats sync.Map
+
+// EnableCompareStats turns the counting of comparisons by type on or off. It
+// is meant for investigating the performance of Compare on real workloads and
+// slows down comparisons considerably while enabled. Counters are kept when
+// stats are disabled; use ResetCompareStats to clear them.
+func EnableCompareStats(enabled bool) {
+	compareStatsEnabled.Store(enabled)
+}
+
+// ResetCompareStats clears the counters returned by CompareStats.
+func ResetCompareStats() {
+	compareStats.Clear()
+}
+
+// CompareStats returns the number of comparisons made while stats were
+// enabled, keyed by the TypeOrder of both operands. Nested values are counted
+// as well, so comparing [1] to [2] counts one comparison of two arrays and
+// one of two numbers. Comparisons with nil are not counted.
+func CompareStats() map[[2]int]uint64 {
+	stats := map[[2]int]uint64{}
+	compareStats.Range(func(k, v any) bool {
+		stats[k.([2]int)] = v.(*atomic.Uint64).Load()
+		return true
+	})
+	return stats
+}
+
+func recordCompareStats(a, b int) {
+	k := [2]int{a, b}
+	v, ok := compareStats.Load(k)
+	if !ok {
+		v, _ = compareStats.LoadOrStore(k, new(atomic.Uint64))
+	}
+	v.(*atomic.Uint64).Add(1)
+}
@@ -0,0 +1,66 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestCompareStats(t *testing.T) {
+	ResetCompareStats()
+	EnableCompareStats(true)
+	t.Cleanup(func() {
+		EnableCompareStats(false)
+		ResetCompareStats()
+	})
+
+	Compare(MustParseTerm(`[1, "a", {2}]`), MustParseTerm(`[1, "b", {3}]`))
+	Compare(IntNumberTerm(1), StringTerm("a"))
+	Compare(NewSet(IntNumberTerm(1), IntNumberTerm(2)), NewSet(IntNumberTerm(1), IntNumberTerm(3)))
+
+	exp := map[[2]int]uint64{
+		{TypeOrderArray, TypeOrderArray}:   1,
+		{TypeOrderNumber, TypeOrderNumber}: 3,
+		{TypeOrderString, TypeOrderString}: 1,
+		{TypeOrderNumber, TypeOrderString}: 1,
+		{TypeOrderSet, TypeOrderSet}:       1,
+	}
+	if act := CompareStats(); !maps.Equal(act, exp) {
+		t.Fatalf("Expected stats %v but got %v", exp, act)
+	}
+
+	EnableCompareStats(false)
+	Compare(IntNumberTerm(1), IntNumberTerm(2))
+	if act := CompareStats(); !maps.Equal(act, exp) {
+		t.Fatalf("Expected stats %v to be unchanged while disabled but got %v", exp, act)
+	}
+
+	ResetCompareStats()
+	if act := CompareStats(); len(act) != 0 {
+		t.Fatalf("Expected no stats after reset but got %v", act)
+	}
+}
+
+func TestCompareStatsLazyObjectsCyclic(t *testing.T) {
+	ResetCompareStats()
+	EnableCompareStats(true)
+	t.Cleanup(func() {
+		EnableCompareStats(false)
+		ResetCompareStats()
+	})
+
+	mk := func(v any) Value {
+		m := map[string]any{"v": v}
+		m["self"] = m
+		return LazyObject(m)
+	}
+	if act := Compare(mk(1), mk(2)); act >= 0 {
+		t.Fatalf("Expected Compare < 0 but got %d", act)
+	}
+	if act := CompareStats()[[2]int{TypeOrderObject, TypeOrderObject}]; act == 0 {
+		t.Fatal("Expected object comparisons to be counted")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"cmp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/collate"
+)
+
+// CompareStringFold compares a and b case-insensitively, using Unicode simple
+// case folding like strings.EqualFold. Strings that are only equal when
+// ignoring case are ordered by their bytes, like Compare, so this is a total
+// order that agrees with Compare on equality: "A" and "a" are adjacent when
+// sorted, but not equal. Use strings.EqualFold to test strings for
+// case-insensitive equality.
+func CompareStringFold(a, b String) int {
+	x, y := string(a), string(b)
+	for x != "" && y != "" {
+		rx, nx := utf8.DecodeRuneInString(x)
+		ry, ny := utf8.DecodeRuneInString(y)
+		if c := cmp.Compare(foldRune(rx), foldRune(ry)); c != 0 {
+			return c
+		}
+		x, y = x[nx:], y[ny:]
+	}
+	if c := cmp.Compare(len(x), len(y)); c != 0 {
+		return c
+	}
+	return strings.Compare(string(a), string(b))
+}
+
+// foldRune returns the smallest rune that r is equivalent to under simple
+// case folding, e.g. 'K' for 'k' and the Kelvin sign.
+func foldRune(r rune) rune {
+	if r < utf8.RuneSelf {
+		if 'a' <= r && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return r
+	}
+	m := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		m = min(m, f)
+	}
+	return m
+}
+
+// SemanticHint tells CompareStringSemantic how to interpret strings.
+type SemanticHint int
+
+const (
+	// SemanticNone compares strings like Compare.
+	SemanticNone SemanticHint = iota
+
+	// SemanticTimestamp compares RFC 3339 timestamps chronologically, e.g.
+	// "2024-01-01T12:00:00+02:00" before "2024-01-01T11:00:00Z".
+	SemanticTimestamp
+
+	// SemanticDuration compares durations like "90s" and "1h30m" by their
+	// length, as parsed by time.ParseDuration.
+	SemanticDuration
+)
+
+// CompareStringSemantic compares a and b as the kind of value that hint
+// describes. Strings that cannot be parsed as that kind sort after all strings
+// that can, and are compared like Compare among themselves, so this is a total
+// order. Strings that denote the same instant or duration but are spelled
+// differently, e.g. in different time zones, are ordered by their bytes, so
+// they are adjacent when sorted, but not equal.
+func CompareStringSemantic(a, b String, hint SemanticHint) int {
+	var c int
+	switch hint {
+	case SemanticTimestamp:
+		c = compareParsed(a, b, func(s string) (time.Time, error) {
+			return time.Parse(time.RFC3339Nano, s)
+		}, time.Time.Compare)
+	case SemanticDuration:
+		c = compareParsed(a, b, time.ParseDuration, cmp.Compare[time.Duration])
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(string(a), string(b))
+}
+
+// compareParsed compares the results of parsing a and b, with strings that
+// cannot be parsed being greater than all strings that can. Two strings that
+// cannot be parsed are equal.
+func compareParsed[T any](a, b String, parse func(string) (T, error), compare func(T, T) int) int {
+	x, errA := parse(string(a))
+	y, errB := parse(string(b))
+	switch {
+	case errA != nil && errB != nil:
+		return 0
+	case errA != nil:
+		return 1
+	case errB != nil:
+		return -1
+	}
+	return compare(x, y)
+}
+
+// CompareStringCollated compares a and b using the collation rules of
+// collator, e.g. so that "a" sorts before "Z" and "ä" next to "a". Strings
+// that collate equally, e.g. because collator ignores case or accents, are
+// ordered by their bytes, so this is a total order. A nil collator compares
+// like Compare. Collators are not safe for concurrent use, so collator must
+// not be shared across goroutines.
+func CompareStringCollated(a, b String, collator *collate.Collator) int {
+	if collator != nil {
+		if c := collator.CompareString(string(a), string(b)); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(string(a), string(b))
+}
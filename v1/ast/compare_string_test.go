@@ -0,0 +1,191 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+func TestCompareStringCollated(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		collator *collate.Collator
+		exp      int
+	}{
+		{"a", "Z", collate.New(language.German), -1},
+		{"ä", "b", collate.New(language.German), -1},
+		{"ä", "z", collate.New(language.German), -1},
+		{"Äpfel", "Apfel", collate.New(language.German), 1},
+		{"öl", "ol", collate.New(language.German), 1},
+		{"öl", "om", collate.New(language.German), -1},
+		// In Swedish, ä and ö are letters of their own, sorted after z.
+		{"ä", "z", collate.New(language.Swedish), 1},
+		{"öl", "om", collate.New(language.Swedish), 1},
+		// Strings that collate equally are ordered by their bytes.
+		{"a", "A", collate.New(language.German, collate.IgnoreCase), 1},
+		{"ä", "a", collate.New(language.German, collate.IgnoreDiacritics), 1},
+		{"a", "a", collate.New(language.German, collate.IgnoreCase), 0},
+		{"a", "Z", nil, 1},
+		{"ä", "b", nil, 1},
+	}
+
+	for _, tc := range tests {
+		a, b := String(tc.a), String(tc.b)
+		if act := CompareStringCollated(a, b, tc.collator); act != tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", tc.exp, a, b, act)
+		}
+		if act := CompareStringCollated(b, a, tc.collator); act != -tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", -tc.exp, b, a, act)
+		}
+	}
+
+	collator := collate.New(language.French, collate.IgnoreCase, collate.IgnoreDiacritics)
+	terms := []*Term{
+		StringTerm("été"),
+		StringTerm("Zoo"),
+		StringTerm("ete"),
+		StringTerm("Été"),
+		StringTerm("abc"),
+		StringTerm("Ete"),
+	}
+	slices.SortFunc(terms, func(a, b *Term) int {
+		return CompareStringCollated(a.Value.(String), b.Value.(String), collator)
+	})
+	exp := []*Term{
+		StringTerm("abc"),
+		StringTerm("Ete"),
+		StringTerm("ete"),
+		StringTerm("Été"),
+		StringTerm("été"),
+		StringTerm("Zoo"),
+	}
+	if !slices.EqualFunc(terms, exp, (*Term).Equal) {
+		t.Fatalf("Expected %v but got %v", exp, terms)
+	}
+}
+
+func TestCompareStringSemantic(t *testing.T) {
+	tests := []struct {
+		a, b string
+		hint SemanticHint
+		exp  int
+	}{
+		// Lexicographically greater, but chronologically earlier.
+		{"2024-01-01T12:00:00+02:00", "2024-01-01T11:00:00Z", SemanticTimestamp, -1},
+		{"2024-01-01T09:00:00-05:00", "2024-01-01T10:00:00Z", SemanticTimestamp, 1},
+		{"2024-01-01T10:00:00.5Z", "2024-01-01T10:00:00.25Z", SemanticTimestamp, 1},
+		{"2024-01-01T10:00:00Z", "2024-01-01T10:00:00Z", SemanticTimestamp, 0},
+		// The same instant is not equal if spelled differently.
+		{"2024-01-01T12:00:00+02:00", "2024-01-01T10:00:00Z", SemanticTimestamp, 1},
+		{"2024-01-01T10:00:00Z", "not a time", SemanticTimestamp, -1},
+		{"not a time", "also not a time", SemanticTimestamp, 1},
+		{"2024-01-01", "2024-01-01T10:00:00Z", SemanticTimestamp, 1},
+		{"90s", "1h", SemanticDuration, -1},
+		{"1h30m", "100m", SemanticDuration, -1},
+		{"-1s", "0s", SemanticDuration, -1},
+		{"60s", "1m", SemanticDuration, 1},
+		{"1d", "1h", SemanticDuration, 1},
+		{"2024-01-01T12:00:00+02:00", "2024-01-01T11:00:00Z", SemanticNone, 1},
+		{"90s", "1h", SemanticNone, 1},
+	}
+
+	for _, tc := range tests {
+		a, b := String(tc.a), String(tc.b)
+		if act := CompareStringSemantic(a, b, tc.hint); act != tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", tc.exp, a, b, act)
+		}
+		if act := CompareStringSemantic(b, a, tc.hint); act != -tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", -tc.exp, b, a, act)
+		}
+		if tc.hint == SemanticNone && CompareStringSemantic(a, b, tc.hint) != Compare(a, b) {
+			t.Errorf("Expected the same result as Compare for %v and %v", a, b)
+		}
+	}
+
+	timestamps := []*Term{
+		StringTerm("2024-01-01T12:00:00+02:00"),
+		StringTerm("invalid"),
+		StringTerm("2024-01-01T09:30:00Z"),
+		StringTerm("2024-01-01T05:00:00-05:00"),
+		StringTerm("2023-12-31T23:59:59.999Z"),
+	}
+	slices.SortFunc(timestamps, func(a, b *Term) int {
+		return CompareStringSemantic(a.Value.(String), b.Value.(String), SemanticTimestamp)
+	})
+	exp := []*Term{
+		StringTerm("2023-12-31T23:59:59.999Z"),
+		StringTerm("2024-01-01T09:30:00Z"),
+		StringTerm("2024-01-01T05:00:00-05:00"),
+		StringTerm("2024-01-01T12:00:00+02:00"),
+		StringTerm("invalid"),
+	}
+	if !slices.EqualFunc(timestamps, exp, (*Term).Equal) {
+		t.Fatalf("Expected %v but got %v", exp, timestamps)
+	}
+}
+
+func TestCompareStringFold(t *testing.T) {
+	tests := []struct {
+		a, b String
+		exp  int
+	}{
+		{"a", "a", 0},
+		{"a", "B", -1},
+		{"A", "a", -1},
+		{"abc", "ABD", -1},
+		{"ab", "AB", 1},
+		{"ab", "ABC", -1},
+		{"\u212a", "k", 1}, // Kelvin sign folds to k
+		{"\u212a", "L", -1},
+		{"\u017f", "t", -1}, // long s folds to s
+		{"\u017f", "S", 1},
+		{"\u0130", "i", 1}, // Turkish dotted capital I does not fold to i
+		{"\u0131", "I", 1}, // Turkish dotless i does not fold to I
+		{"\u0131", "j", 1},
+		{"\u00c4", "\u00e4", -1},
+		{"\u00e4", "\u00c5", -1},
+	}
+	for _, tc := range tests {
+		if act := CompareStringFold(tc.a, tc.b); act != tc.exp {
+			t.Errorf("Expected CompareStringFold(%v, %v) == %d but got %d", tc.a, tc.b, tc.exp, act)
+		}
+		if act := CompareStringFold(tc.b, tc.a); act != -tc.exp {
+			t.Errorf("Expected CompareStringFold(%v, %v) == %d but got %d", tc.b, tc.a, -tc.exp, act)
+		}
+	}
+
+	// Strings that are equal under strings.EqualFold are adjacent when sorted.
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []rune("aAbBkK\u212asS\u017fiI\u0130\u0131")
+	strs := make([]String, 500)
+	for i := range strs {
+		rs := make([]rune, rng.Intn(3))
+		for j := range rs {
+			rs[j] = alphabet[rng.Intn(len(alphabet))]
+		}
+		strs[i] = String(rs)
+	}
+	slices.SortFunc(strs, CompareStringFold)
+	for i := range strs {
+		for j := i + 2; j < len(strs); j++ {
+			if strings.EqualFold(string(strs[i]), string(strs[j])) && !strings.EqualFold(string(strs[i]), string(strs[j-1])) {
+				t.Fatalf("Expected %q and %q to be adjacent to each other, but found %q between them", strs[i], strs[j], strs[j-1])
+			}
+		}
+	}
+
+	if act := CompareWith(MustParseTerm(`["b", "a"]`), MustParseTerm(`["B", "A"]`), CompareOptions{StringFold: true}); act != 1 {
+		t.Errorf("Expected strings that only differ in case to be ordered by bytes but got %d", act)
+	}
+	if act := CompareWith(MustParseTerm(`["a"]`), MustParseTerm(`["B"]`), CompareOptions{StringFold: true}); act != -1 {
+		t.Errorf("Expected strings to be compared case-insensitively but got %d", act)
+	}
+}
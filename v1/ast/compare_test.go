@@ -5,6 +5,14 @@
 package ast
 
 import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -785,3 +793,1026 @@ func TestCompareAnnotations(t *testing.T) {
 		})
 	}
 }
+
+func TestCompareEmptyCollections(t *testing.T) {
+	// Ordered by type: array < object < set, with both object implementations
+	// being equal.
+	empties := []struct {
+		note  string
+		value Value
+		rank  int
+	}{
+		{"array", NewArray(), 0},
+		{"object", NewObject(), 1},
+		{"lazy object", LazyObject(map[string]any{}), 1},
+		{"set", NewSet(), 2},
+	}
+	nonEmpty := []Value{NewArray(NullTerm()), NewObject(Item(NullTerm(), NullTerm())), LazyObject(map[string]any{"a": nil}), NewSet(NullTerm())}
+
+	compares := map[string]func(a, b Value) int{
+		"Compare":       func(a, b Value) int { return Compare(a, b) },
+		"Value.Compare": func(a, b Value) int { return a.Compare(b) },
+		"CompareWith":   func(a, b Value) int { return CompareWith(a, b, CompareOptions{SetsByCardinality: true}) },
+	}
+
+	for name, compare := range compares {
+		for _, x := range empties {
+			for _, y := range empties {
+				if act, exp := compare(x.value, y.value), cmp.Compare(x.rank, y.rank); act != exp {
+					t.Errorf("%s: expected %d for empty %s and empty %s but got %d", name, exp, x.note, y.note, act)
+				}
+				if act, exp := ValueEqual(x.value, y.value), x.rank == y.rank; act != exp {
+					t.Errorf("expected ValueEqual to be %v for empty %s and empty %s", exp, x.note, y.note)
+				}
+			}
+			// An empty collection is less than any non-empty collection of the
+			// same type, and ordered by type otherwise.
+			for i, v := range nonEmpty {
+				exp := cmp.Compare(x.rank, []int{0, 1, 1, 2}[i])
+				if exp == 0 {
+					exp = -1
+				}
+				if act := compare(x.value, v); act != exp {
+					t.Errorf("%s: expected %d for empty %s and %v but got %d", name, exp, x.note, v, act)
+				}
+				if act := compare(v, x.value); act != -exp {
+					t.Errorf("%s: expected %d for %v and empty %s but got %d", name, -exp, v, x.note, act)
+				}
+			}
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		a, b any
+		exp  Ordering
+	}{
+		{IntNumberTerm(1), IntNumberTerm(2), OrderLess},
+		{NumberTerm("1.0"), IntNumberTerm(1), OrderEqual},
+		{StringTerm("b"), StringTerm("a"), OrderGreater},
+		{String("a"), String("abc"), OrderLess},
+		{MustParseTerm(`[1, 2, 3]`), MustParseTerm(`[1, 2]`), OrderGreater},
+		{NullTerm(), BooleanTerm(false), OrderLess},
+		{NewSet(), NewObject(), OrderGreater},
+		{nil, nil, OrderEqual},
+	}
+	for _, tc := range tests {
+		if act := Order(tc.a, tc.b); act != tc.exp {
+			t.Errorf("Expected %v for %v and %v but got %v", tc.exp, tc.a, tc.b, act)
+		}
+		if act := Order(tc.b, tc.a); int(act) != -int(tc.exp) {
+			t.Errorf("Expected %v for %v and %v but got %v", -tc.exp, tc.b, tc.a, act)
+		}
+	}
+
+	for o, exp := range map[Ordering]string{OrderLess: "less", OrderEqual: "equal", OrderGreater: "greater", 2: "Ordering(2)"} {
+		if o.String() != exp {
+			t.Errorf("Expected %q but got %q", exp, o.String())
+		}
+	}
+}
+
+func TestCompareNilValueTerm(t *testing.T) {
+	nilValue := &Term{}
+	tests := []struct {
+		a, b any
+		exp  int
+	}{
+		{nilValue, nilValue, 0},
+		{nilValue, &Term{}, 0},
+		{nilValue, nil, 0},
+		{nil, nilValue, 0},
+		{nilValue, (*Term)(nil), 0},
+		{nilValue, NullTerm(), -1},
+		{NullTerm(), nilValue, 1},
+		{nilValue, Boolean(false), -1},
+		{Ref{VarTerm("x"), nilValue}, Ref{VarTerm("x"), NullTerm()}, -1},
+		{Ref{VarTerm("x"), NullTerm()}, Ref{VarTerm("x"), nilValue}, 1},
+		{Call{nilValue}, Call{&Term{}}, 0},
+	}
+	for _, tc := range tests {
+		if act := Compare(tc.a, tc.b); act != tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", tc.exp, tc.a, tc.b, act)
+		}
+		if act, err := CompareErr(tc.a, tc.b); err != nil || act != tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d (err: %v)", tc.exp, tc.a, tc.b, act, err)
+		}
+	}
+}
+
+func TestCompareErr(t *testing.T) {
+	tests := []struct {
+		note string
+		a    any
+		b    any
+		exp  int
+		err  string
+	}{
+		{note: "valid", a: NumberTerm("1"), b: NumberTerm("2"), exp: -1},
+		{note: "unknown type", a: 42, b: NullTerm(), err: "illegal value: int"},
+		{note: "malformed number left", a: Number("1.2.3"), b: Number("1.5"), err: `illegal value: ast.Number "1.2.3"`},
+		{note: "malformed number right", a: Number("1.5"), b: Number("foo"), err: `illegal value: ast.Number "foo"`},
+		{note: "malformed number nested", a: ArrayTerm(NumberTerm("0.5")), b: ArrayTerm(NumberTerm("x")), err: `illegal value: ast.Number "x"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			cmp, err := CompareErr(tc.a, tc.b)
+			if tc.err != "" {
+				var uerr *UnsupportedValueError
+				if !errors.As(err, &uerr) {
+					t.Fatalf("Expected *UnsupportedValueError but got: %v", err)
+				}
+				if err.Error() != tc.err {
+					t.Fatalf("Expected error %q but got %q", tc.err, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if cmp != tc.exp {
+				t.Fatalf("Expected %d but got %d", tc.exp, cmp)
+			}
+		})
+	}
+}
+
+func TestCompareStrict(t *testing.T) {
+	tests := []struct {
+		note string
+		a, b any
+		exp  int
+		err  string
+	}{
+		{note: "equal objects", a: MustParseTerm(`{"a": 1, "b": 2}`), b: MustParseTerm(`{"b": 2, "a": 1.0}`)},
+		{note: "equal sets", a: MustParseTerm(`{1, 2}`), b: MustParseTerm(`{2.0, 1}`)},
+		{note: "equal lazy object", a: LazyObject(map[string]any{"a": 1}), b: MustParseTerm(`{"a": 1}`)},
+		{note: "unequal objects", a: MustParseTerm(`{"a": 1}`), b: MustParseTerm(`{"a": 2}`), err: `undefined order of unequal objects: {"a": 1} and {"a": 2}`},
+		{note: "unequal sets", a: MustParseTerm(`{1, 2}`), b: MustParseTerm(`{1}`), err: `undefined order of unequal sets: {1, 2} and {1}`},
+		{note: "nested in object", a: MustParseTerm(`{"a": {1}}`), b: MustParseTerm(`{"a": {2}}`), err: `undefined order of unequal objects: {"a": {1}} and {"a": {2}}`},
+		{note: "nested in array", a: MustParseTerm(`[1, {"a": [{1}]}]`), b: MustParseTerm(`[1, {"a": [{2}]}, 0]`), err: `undefined order of unequal objects: {"a": [{1}]} and {"a": [{2}]}`},
+		{note: "nested in ref", a: MustParseRef(`data.a[{1}]`), b: MustParseRef(`data.a[{2}]`), err: `undefined order of unequal sets: {1} and {2}`},
+		{note: "array decided before set", a: MustParseTerm(`[1, {1}]`), b: MustParseTerm(`[2, {2}]`), exp: -1},
+		{note: "array length", a: MustParseTerm(`[{1}]`), b: MustParseTerm(`[{1}, 2]`), exp: -1},
+		{note: "call arity", a: CallTerm(VarTerm("f"), SetTerm(IntNumberTerm(2))), b: CallTerm(VarTerm("f"), SetTerm(), IntNumberTerm(1)), exp: -1},
+		{note: "object and set", a: MustParseTerm(`{"a": 1}`), b: MustParseTerm(`{1}`), exp: -1},
+		{note: "scalars", a: MustParseTerm(`"b"`), b: MustParseTerm(`"a"`), exp: 1},
+		{note: "unknown type", a: 42, b: SetTerm(), err: "illegal value: int"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			cmp, err := CompareStrict(tc.a, tc.b)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected error %q but got: %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if cmp != tc.exp {
+				t.Fatalf("Expected %d but got %d", tc.exp, cmp)
+			}
+		})
+	}
+
+	var uerr *UndefinedOrderError
+	if _, err := CompareStrict(SetTerm(), SetTerm(NullTerm())); !errors.As(err, &uerr) {
+		t.Fatalf("Expected *UndefinedOrderError but got: %v", err)
+	}
+}
+
+// customValue is a Value defined outside of the types known to Compare. Its
+// rank is given by order, and values with the same rank compare by n.
+type customValue struct {
+	order int
+	n     int
+}
+
+func (v customValue) Compare(other Value) int { return Compare(v, other) }
+func (customValue) Find(Ref) (Value, error)   { return nil, errFindNotFound }
+func (v customValue) Hash() int               { return v.n }
+func (customValue) IsGround() bool            { return true }
+func (v customValue) String() string          { return fmt.Sprintf("custom(%d, %d)", v.order, v.n) }
+func (v customValue) SortOrder() int          { return v.order }
+func (v customValue) CompareValue(other Value) int {
+	return cmp.Compare(v.n, other.(customValue).n)
+}
+
+func TestCompareOrderedValue(t *testing.T) {
+	tests := []struct {
+		a, b any
+		exp  int
+	}{
+		{customValue{0, 1}, customValue{0, 1}, 0},
+		{customValue{0, 1}, customValue{0, 2}, -1},
+		{customValue{1, 1}, customValue{0, 2}, 1},
+		{MustParseModule("package a"), customValue{0, 0}, -1},
+		{customValue{0, 0}, NullTerm(), 1},
+		{ArrayTerm(NewTerm(customValue{0, 3})), ArrayTerm(NewTerm(customValue{0, 2})), 1},
+		{SetTerm(NewTerm(customValue{0, 1}), IntNumberTerm(1)), SetTerm(IntNumberTerm(1), NewTerm(customValue{0, 1})), 0},
+	}
+
+	for _, tc := range tests {
+		if act := Compare(tc.a, tc.b); act != tc.exp {
+			t.Errorf("Expected Compare(%v, %v) == %d but got %d", tc.a, tc.b, tc.exp, act)
+		}
+	}
+
+	if act := TypeOrder(customValue{2, 0}); act != TypeOrderCustom+2 {
+		t.Errorf("Expected rank %d but got %d", TypeOrderCustom+2, act)
+	}
+
+	var uerr *UnsupportedValueError
+	if _, err := CompareErr(customValue{-1, 0}, NullTerm()); !errors.As(err, &uerr) {
+		t.Errorf("Expected *UnsupportedValueError for negative sort order but got: %v", err)
+	}
+}
+
+func TestComparePanicsOnIllegalValue(t *testing.T) {
+	tests := []struct {
+		note string
+		a, b any
+		exp  string
+	}{
+		{note: "unknown type", a: 42, b: NullTerm(), exp: "illegal value: int"},
+		{note: "malformed number", a: Number("1.2.3"), b: Number("1.5"), exp: "illegal value"},
+		{note: "malformed number nested", a: ArrayTerm(NumberTerm("0.5")), b: ArrayTerm(NumberTerm("x")), exp: "illegal value"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != tc.exp {
+					t.Fatalf("Expected panic with %q but got: %v", tc.exp, r)
+				}
+			}()
+			Compare(tc.a, tc.b)
+		})
+	}
+}
+
+func TestCompareFuncs(t *testing.T) {
+	terms := []*Term{StringTerm("b"), NumberTerm("1.5"), nil, StringTerm("a"), NullTerm()}
+	slices.SortFunc(terms, TermCompareFunc())
+	if !slices.IsSortedFunc(terms, func(a, b *Term) int { return Compare(a, b) }) {
+		t.Fatalf("Expected terms to be sorted but got %v", terms)
+	}
+	if terms[0] != nil {
+		t.Fatalf("Expected nil term first but got %v", terms[0])
+	}
+
+	values := []Value{String("b"), Number("1.5"), String("a"), Null{}}
+	slices.SortFunc(values, ValueCompareFunc())
+	exp := []Value{Null{}, Number("1.5"), String("a"), String("b")}
+	if !slices.EqualFunc(values, exp, ValueEqual) {
+		t.Fatalf("Expected %v but got %v", exp, values)
+	}
+}
+
+func BenchmarkValueEqualSet(b *testing.B) {
+	const n = 50000
+	x, y, z := NewSet(), NewSet(), NewSet()
+	for i := range n {
+		x.Add(IntNumberTerm(i))
+		y.Add(IntNumberTerm(n - i - 1))
+		z.Add(IntNumberTerm(i))
+	}
+	// z differs from x only in its greatest element.
+	z = z.Diff(NewSet(IntNumberTerm(n - 1)))
+	z.Add(IntNumberTerm(n))
+	// Make sure the sets are not sorted when the benchmark starts.
+	x, y, z = x.Copy(), y.Copy(), z.Copy()
+
+	b.Run("equal", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			if !ValueEqual(x, y) {
+				b.Fatal("expected equal")
+			}
+		}
+	})
+	b.Run("not equal", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			if ValueEqual(x, z) {
+				b.Fatal("expected not equal")
+			}
+		}
+	})
+}
+
+func TestValueEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  bool
+	}{
+		{`{1, 2, 3}`, `{3, 2, 1}`, true},
+		{`{1, 2, 3}`, `{1.0, 2, 3e0}`, true},
+		{`{1, 2, 3}`, `{1, 2, 4}`, false},
+		{`{1, 2, 3}`, `{1, 2}`, false},
+		{`set()`, `set()`, true},
+		{`{1, 2}`, `[1, 2]`, false},
+		{`{"a": 1, "b": {2}}`, `{"b": {2}, "a": 1.0}`, true},
+		{`{"a": 1, "b": {2}}`, `{"b": {3}, "a": 1}`, false},
+		{`{"a": 1, "b": 2}`, `{"a": 1, "c": 2}`, false},
+		{`{"a": 1}`, `{"a": 1, "b": 2}`, false},
+		{`[x | x = 1]`, `[x | x = 1]`, true},
+		{`[x | x = 1]`, `[y | y = 1]`, false},
+		{`{x | x = 1}`, `{x | x = 1}`, true},
+		{`{x | x = 1}`, `[x | x = 1]`, false},
+		{`{k: v | k = 1; v = 2}`, `{k: v | k = 1; v = 2}`, true},
+		{`{k: v | k = 1; v = 2}`, `{v: k | k = 1; v = 2}`, false},
+		{`f(1, x)`, `f(1.0, x)`, true},
+		{`f(1, x)`, `f(1, y)`, false},
+		{`f(1, x)`, `g(1, x)`, false},
+	}
+
+	parse := func(s string) Value {
+		// Calls are parsed as expressions when they appear on their own.
+		if expr, ok := MustParseStatement(s).(Body); ok && expr[0].IsCall() {
+			return Call(expr[0].Terms.([]*Term))
+		}
+		return MustParseTerm(s).Value
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.a+"_"+tc.b, func(t *testing.T) {
+			a, b := parse(tc.a), parse(tc.b)
+			if act := ValueEqual(a, b); act != tc.exp {
+				t.Errorf("Expected ValueEqual(%v, %v) == %v but got %v", a, b, tc.exp, act)
+			}
+			if act := ValueEqual(a, b); act != (Compare(a, b) == 0) {
+				t.Errorf("Expected ValueEqual(%v, %v) to agree with Compare", a, b)
+			}
+		})
+	}
+}
+
+func TestValueEqualLazyObject(t *testing.T) {
+	lazy := LazyObject(map[string]any{"a": json.Number("1"), "b": map[string]any{"c": "d"}})
+	tests := []struct {
+		obj string
+		exp bool
+	}{
+		{`{"a": 1, "b": {"c": "d"}}`, true},
+		{`{"a": 1.0, "b": {"c": "d"}}`, true},
+		{`{"a": 1, "b": {"c": "e"}}`, false},
+		{`{"a": 1, "c": {"c": "d"}}`, false},
+		{`{"a": 1}`, false},
+	}
+
+	for _, tc := range tests {
+		obj := MustParseTerm(tc.obj).Value
+		if act := ValueEqual(obj, lazy); act != tc.exp {
+			t.Errorf("Expected ValueEqual(%v, %v) == %v but got %v", obj, lazy, tc.exp, act)
+		}
+	}
+}
+
+func BenchmarkCompareRefSelf(b *testing.B) {
+	ref := MustParseRef(`data.foo.bar[x].baz.qux[1]["quux"]`)
+
+	// copy is the baseline of comparing the elements of an equal ref that
+	// does not share its backing array.
+	for _, tc := range []struct {
+		name  string
+		other Ref
+	}{{"self", ref}, {"copy", ref.Copy()}} {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if Compare(ref, tc.other) != 0 {
+					b.Fatal("expected equal")
+				}
+			}
+		})
+	}
+}
+
+func TestTermSliceCompareAliased(t *testing.T) {
+	ref := MustParseRef(`data.foo.bar[1].baz`)
+	other := ref.Copy()
+
+	tests := []struct {
+		note string
+		a, b []*Term
+		exp  int
+	}{
+		{"identical", ref, ref, 0},
+		{"aliased shorter", ref[:2], ref, -1},
+		{"aliased longer", ref, ref[:3], 1},
+		{"aliased empty", ref[:0], ref, -1},
+		{"aliased offset", ref[1:], ref, -1},
+		{"copy", ref, other, 0},
+		{"copy shorter", ref[:2], other, -1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			if act := termSliceCompare(tc.a, tc.b); act != tc.exp {
+				t.Errorf("Expected %d but got %d", tc.exp, act)
+			}
+			if act := termSliceCompare(tc.b, tc.a); act != -tc.exp {
+				t.Errorf("Expected %d for reversed operands but got %d", -tc.exp, act)
+			}
+		})
+	}
+}
+
+// TestCompareTotalOrder checks that Compare is a total order on a sample of
+// values of all types, including objects that are lazy, forced or neither, and
+// values nested in them: it must be reflexive, antisymmetric and transitive,
+// even where the order itself is unspecified.
+func TestCompareTotalOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var pool []Value
+	for len(pool) < 250 {
+		v := GenRandomValue(rng, 2)
+		pool = append(pool, v, respell(rng, v))
+		if obj, ok := v.(Object); ok {
+			native, err := JSON(obj)
+			if err != nil {
+				continue
+			}
+			lazy := LazyObject(native.(map[string]any))
+			forced := LazyObject(native.(map[string]any))
+			forced.(*lazyObj).force()
+			pool = append(pool, lazy, forced,
+				NewArray(NewTerm(lazy), IntNumberTerm(1)),
+				NewSet(NewTerm(forced), NewTerm(obj)),
+			)
+		}
+	}
+	for _, s := range []string{`x`, `y`, `_`, `data.a[x]`, `data.a.b`, `input.a`, `[x | x = 1]`, `{x | x = 1}`, `{x: 1 | x = 1}`} {
+		pool = append(pool, MustParseTerm(s).Value)
+	}
+	pool = append(pool,
+		Call{RefTerm(VarTerm("plus")), IntNumberTerm(1), IntNumberTerm(2)},
+		Call{RefTerm(VarTerm("plus")), IntNumberTerm(1)},
+		LazyObject(map[string]any{}),
+		LazyObject(map[string]any{"a": map[string]any{"b": json.Number("1")}}),
+		LazyObject(map[string]any{"a": map[string]any{"b": json.Number("1.0"), "c": "x"}}),
+	)
+
+	n := len(pool)
+	c := make([][]int, n)
+	for i := range pool {
+		c[i] = make([]int, n)
+		for j := range pool {
+			c[i][j] = cmp.Compare(Compare(pool[i], pool[j]), 0)
+		}
+	}
+
+	for i := range n {
+		if c[i][i] != 0 {
+			t.Fatalf("Expected %v to equal itself but got %d", pool[i], c[i][i])
+		}
+		for j := range n {
+			if c[i][j] != -c[j][i] {
+				t.Fatalf("Expected antisymmetry for %v and %v but got %d and %d", pool[i], pool[j], c[i][j], c[j][i])
+			}
+			if c[i][j] > 0 {
+				continue
+			}
+			for k := range n {
+				if c[j][k] <= 0 && c[i][k] > 0 {
+					t.Fatalf("Expected transitivity for %v <= %v <= %v but got %d", pool[i], pool[j], pool[k], c[i][k])
+				}
+			}
+		}
+	}
+}
+
+// respell returns a value that compares equal to v but is constructed
+// differently: numbers are spelled differently, and the elements of objects
+// and sets are inserted in a different order.
+func respell(rng *rand.Rand, v Value) Value {
+	switch v := v.(type) {
+	case Number:
+		s := string(v)
+		switch {
+		case strings.ContainsAny(s, "eE"):
+			return v
+		case strings.Contains(s, "."):
+			return Number(s + "0")
+		case rng.Intn(2) == 0:
+			return Number(s + ".0")
+		default:
+			return Number(s + "e0")
+		}
+	case *Array:
+		elems := make([]*Term, v.Len())
+		for i := range elems {
+			elems[i] = NewTerm(respell(rng, v.Elem(i).Value))
+		}
+		return NewArray(elems...)
+	case Object:
+		keys := v.Keys()
+		rng.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+		obj := NewObject()
+		for _, k := range keys {
+			obj.Insert(NewTerm(respell(rng, k.Value)), NewTerm(respell(rng, v.Get(k).Value)))
+		}
+		return obj
+	case Set:
+		elems := slices.Clone(v.Slice())
+		rng.Shuffle(len(elems), func(i, j int) { elems[i], elems[j] = elems[j], elems[i] })
+		set := NewSet()
+		for _, e := range elems {
+			set.Add(NewTerm(respell(rng, e.Value)))
+		}
+		return set
+	}
+	return v
+}
+
+func BenchmarkCompareLazyObjects(b *testing.B) {
+	const n = 100000
+	m1 := make(map[string]any, n)
+	m2 := make(map[string]any, n)
+	for i := range n {
+		k := fmt.Sprintf("key%06d", i)
+		m1[k] = map[string]any{"value": i}
+		m2[k] = map[string]any{"value": i}
+	}
+	// The objects differ in their first sorted key.
+	m2["key000000"] = map[string]any{"value": -1}
+
+	b.ReportAllocs()
+	for range b.N {
+		b.StopTimer()
+		x, y := LazyObject(m1), LazyObject(m2)
+		b.StartTimer()
+		if Compare(x, y) <= 0 {
+			b.Fatal("expected greater")
+		}
+	}
+}
+
+func TestCompareLazyObjects(t *testing.T) {
+	tests := []struct {
+		note string
+		a, b map[string]any
+	}{
+		{
+			note: "equal",
+			a:    map[string]any{"a": 1, "b": map[string]any{"c": "d"}},
+			b:    map[string]any{"a": 1, "b": map[string]any{"c": "d"}},
+		},
+		{
+			note: "differing first key",
+			a:    map[string]any{"a": 1, "b": 2},
+			b:    map[string]any{"aa": 1, "b": 2},
+		},
+		{
+			note: "differing nested value",
+			a:    map[string]any{"a": 1, "b": map[string]any{"c": "d"}},
+			b:    map[string]any{"a": 1, "b": map[string]any{"c": "e"}},
+		},
+		{
+			note: "prefix",
+			a:    map[string]any{"a": 1, "b": 2},
+			b:    map[string]any{"a": 1, "b": 2, "c": 3},
+		},
+		{
+			note: "empty",
+			a:    map[string]any{},
+			b:    map[string]any{"a": []any{1, 2}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			exp := Compare(MustInterfaceToValue(tc.a), MustInterfaceToValue(tc.b))
+			for _, pair := range [][2]Value{
+				{LazyObject(tc.a), LazyObject(tc.b)},
+				{LazyObject(tc.a), MustInterfaceToValue(tc.b)},
+				{MustInterfaceToValue(tc.a), LazyObject(tc.b)},
+			} {
+				if act := Compare(pair[0], pair[1]); act != exp {
+					t.Errorf("Expected Compare(%v, %v) == %d but got %d", pair[0], pair[1], exp, act)
+				}
+				if act := Compare(pair[1], pair[0]); act != -exp {
+					t.Errorf("Expected Compare(%v, %v) == %d but got %d", pair[1], pair[0], -exp, act)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareLazyObjectsDoesNotForce(t *testing.T) {
+	a := LazyObject(map[string]any{"a": 1, "b": map[string]any{"c": 1}}).(*lazyObj)
+	b := LazyObject(map[string]any{"a": 2, "b": map[string]any{"c": 1}}).(*lazyObj)
+
+	if Compare(a, b) >= 0 {
+		t.Fatal("Expected a to be less than b")
+	}
+	if a.strict != nil || b.strict != nil {
+		t.Fatal("Expected lazy objects not to be forced")
+	}
+	if _, ok := a.cache["b"]; ok {
+		t.Fatal("Expected value of key not inspected to not be converted")
+	}
+}
+
+// assertLazyForcedConsistent checks that o compares the same as a forced copy
+// of it: equal to the copy, and in the same way against each of others, in
+// both directions. o itself is not forced.
+func assertLazyForcedConsistent(t *testing.T, o *lazyObj, others ...Value) {
+	t.Helper()
+	forced := LazyObject(o.native).(*lazyObj)
+	forced.force()
+	for _, f := range []Value{forced, MustInterfaceToValue(o.native)} {
+		if c := Compare(o, f); c != 0 {
+			t.Fatalf("Expected %v to equal its forced form but got %d", o, c)
+		}
+		if c := Compare(f, o); c != 0 {
+			t.Fatalf("Expected forced form of %v to equal it but got %d", o, c)
+		}
+		for _, other := range others {
+			if lc, fc := Compare(o, other), Compare(f, other); lc != fc {
+				t.Fatalf("Expected %v to compare to %v like its forced form (%d) but got %d", o, other, fc, lc)
+			}
+			if lc, fc := Compare(other, o), Compare(other, f); lc != fc {
+				t.Fatalf("Expected %v to compare to %v like to its forced form (%d) but got %d", other, o, fc, lc)
+			}
+		}
+	}
+	if o.strict != nil {
+		t.Fatal("Expected lazy object not to be forced")
+	}
+}
+
+func TestCompareLazyForcedConsistent(t *testing.T) {
+	// Keys whose byte order differs from their numeric, case-insensitive or
+	// length order, and values of various Go types.
+	natives := []map[string]any{
+		{},
+		{"10": 1, "9": 2, "1": 3, "": 4},
+		{"b": 1, "B": 2, "a": 3, "A": 4, "_": 5},
+		{"aa": 1, "b": 2, "a": 3, "ab": 4},
+		{"é": 1, "z": 2, "e\u0301": 3, "ﬁ": 4, "\xff": 5},
+		{"-1": -1, "+1": 1, "1e2": 100, "0x1": 1},
+		{"a": map[string]any{"10": 1, "9": map[string]any{"x": []any{1, "y"}}}},
+		{"a": map[string]string{"z": "1", "a": "2"}, "b": map[string]int{"2": 1, "10": 2}},
+		{"a": []any{map[string]any{"k": nil}, true}, "b": json.Number("1.0"), "c": 1.5, "d": int64(2)},
+		{"a": map[string]any{"b": map[string]any{"c": map[string]any{}}}},
+	}
+
+	var others []Value
+	for _, native := range natives {
+		others = append(others, LazyObject(native), MustInterfaceToValue(native))
+	}
+	others = append(others,
+		MustParseTerm(`{"10": 1, "9": 2, "1": 3, "": 5}`).Value,
+		MustParseTerm(`{"a": {"10": 1, "9": {"x": [1, "z"]}}}`).Value,
+		MustParseTerm(`{1: 1}`).Value,
+		MustParseTerm(`{"a": 1}`).Value,
+		MustParseTerm(`[1]`).Value,
+		NewArray(NewTerm(LazyObject(natives[6]))),
+	)
+
+	for _, native := range natives {
+		assertLazyForcedConsistent(t, LazyObject(native).(*lazyObj), others...)
+
+		// Partially converted lazy objects compare the same as fresh ones.
+		partial := LazyObject(native).(*lazyObj)
+		for k := range native {
+			partial.Get(StringTerm(k))
+			break
+		}
+		assertLazyForcedConsistent(t, partial, others...)
+	}
+}
+
+func TestCompareLazyObjectsCyclic(t *testing.T) {
+	direct := func(v any) map[string]any {
+		m := map[string]any{"v": v}
+		m["self"] = m
+		return m
+	}
+	wrapped := func(v any) map[string]any {
+		m := map[string]any{"v": v}
+		m["self"] = LazyObject(m)
+		return m
+	}
+
+	for name, mk := range map[string]func(any) map[string]any{"direct": direct, "wrapped": wrapped} {
+		t.Run(name, func(t *testing.T) {
+			a, b, c := LazyObject(mk(1)), LazyObject(mk(1)), LazyObject(mk(2))
+			if act := Compare(a, a); act != 0 {
+				t.Errorf("Expected Compare(a, a) == 0 but got %d", act)
+			}
+			if act := Compare(a, b); act != 0 {
+				t.Errorf("Expected Compare(a, b) == 0 but got %d", act)
+			}
+			if act := Compare(a, c); act >= 0 {
+				t.Errorf("Expected Compare(a, c) < 0 but got %d", act)
+			}
+			if act := Compare(c, a); act <= 0 {
+				t.Errorf("Expected Compare(c, a) > 0 but got %d", act)
+			}
+		})
+	}
+}
+
+func TestTypeOrder(t *testing.T) {
+	values := []any{
+		NullTerm().Value,
+		BooleanTerm(true).Value,
+		NumberTerm("1").Value,
+		StringTerm("a").Value,
+		VarTerm("x").Value,
+		MustParseRef("data.x"),
+		NewArray(),
+		NewObject(),
+		LazyObject(map[string]any{}),
+		NewSet(),
+		MustParseTerm(`[x | x = 1]`).Value,
+		MustParseTerm(`{x: x | x = 1}`).Value,
+		MustParseTerm(`{x | x = 1}`).Value,
+		Call{RefTerm(VarTerm("f"))},
+		Args{},
+		MustParseExpr(`x = 1`),
+		&SomeDecl{},
+		&Every{},
+		&With{},
+		&Head{},
+		NewBody(),
+		&Rule{},
+		&Import{},
+		&Package{},
+		&Annotations{},
+		&Module{},
+	}
+	exp := []int{
+		TypeOrderNull, TypeOrderBoolean, TypeOrderNumber, TypeOrderString,
+		TypeOrderVar, TypeOrderRef, TypeOrderArray, TypeOrderObject,
+		TypeOrderObject, TypeOrderSet, TypeOrderArrayComprehension,
+		TypeOrderObjectComprehension, TypeOrderSetComprehension, TypeOrderCall,
+		TypeOrderArgs, TypeOrderExpr, TypeOrderSomeDecl, TypeOrderEvery,
+		TypeOrderWith, TypeOrderHead, TypeOrderBody, TypeOrderRule,
+		TypeOrderImport, TypeOrderPackage, TypeOrderAnnotations, TypeOrderModule,
+	}
+
+	for i, v := range values {
+		if act := TypeOrder(v); act != exp[i] {
+			t.Errorf("Expected TypeOrder(%T) == %d but got %d", v, exp[i], act)
+		}
+		if i > 0 && exp[i-1] < exp[i] && Compare(values[i-1], v) >= 0 {
+			t.Errorf("Expected %T to be less than %T", values[i-1], v)
+		}
+	}
+}
+
+func BenchmarkCompareSetOfMixedTerms(b *testing.B) {
+	build := func() Set {
+		set := NewSet()
+		for i := range 1000 {
+			set.Add(ArrayTerm(
+				NullTerm(),
+				BooleanTerm(i%2 == 0),
+				StringTerm("x"),
+				ArrayTerm(NullTerm(), BooleanTerm(true), StringTerm("y")),
+				SetTerm(StringTerm("z"), BooleanTerm(false)),
+				StringTerm(strconv.Itoa(i)),
+			))
+		}
+		return set
+	}
+	x, y := build(), build()
+	if Compare(x, y) != 0 {
+		b.Fatal("expected sets to be equal")
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		Compare(x, y)
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		a, b []Value
+		exp  int
+	}{
+		{nil, nil, 0},
+		{nil, []Value{}, 0},
+		{[]Value{String("a"), Number("1")}, []Value{String("a"), Number("1.0")}, 0},
+		{[]Value{String("a"), Number("1")}, []Value{String("a"), Number("2")}, -1},
+		{[]Value{String("b"), Number("1")}, []Value{String("a"), Number("2")}, 1},
+		{[]Value{MustParseRef("data.a"), Var("p"), Number("2")}, []Value{MustParseRef("data.a"), Var("p"), Number("1")}, 1},
+		{[]Value{Number("1"), String("a")}, []Value{String("a"), Number("1")}, -1},
+		{[]Value{Null{}, NewArray()}, []Value{Null{}, NewObject()}, -1},
+		{[]Value{String("a")}, []Value{String("a"), Null{}}, -1},
+		{[]Value{String("b")}, []Value{String("a"), Null{}}, 1},
+		{[]Value{nil, String("a")}, []Value{Null{}}, -1},
+		{[]Value{nil}, []Value{nil}, 0},
+	}
+
+	for _, tc := range tests {
+		if act := CompareValues(tc.a, tc.b); act != tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", tc.exp, tc.a, tc.b, act)
+		}
+		if act := CompareValues(tc.b, tc.a); act != -tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", -tc.exp, tc.b, tc.a, act)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for range 100 {
+		a := make([]Value, rng.Intn(4))
+		b := make([]Value, rng.Intn(4))
+		for i := range a {
+			a[i] = GenRandomValue(rng, 1)
+		}
+		for i := range b {
+			b[i] = GenRandomValue(rng, 1)
+		}
+		exp := Compare(NewArray(valueTerms(a)...), NewArray(valueTerms(b)...))
+		if act := CompareValues(a, b); act != exp {
+			t.Fatalf("Expected %d for %v and %v like their arrays but got %d", exp, a, b, act)
+		}
+	}
+}
+
+func valueTerms(vs []Value) []*Term {
+	terms := make([]*Term, len(vs))
+	for i, v := range vs {
+		terms[i] = NewTerm(v)
+	}
+	return terms
+}
+
+func TestCompareTermSlice(t *testing.T) {
+	tests := []struct {
+		a, b []*Term
+		exp  int
+	}{
+		{nil, nil, 0},
+		{nil, []*Term{IntNumberTerm(1)}, -1},
+		{[]*Term{IntNumberTerm(1)}, []*Term{NumberTerm("1.0")}, 0},
+		{[]*Term{IntNumberTerm(1), StringTerm("a")}, []*Term{IntNumberTerm(1)}, 1},
+		{[]*Term{IntNumberTerm(1), StringTerm("a")}, []*Term{IntNumberTerm(2)}, -1},
+		{[]*Term{VarTerm("x"), StringTerm("b")}, []*Term{VarTerm("x"), StringTerm("a")}, 1},
+	}
+
+	for _, tc := range tests {
+		if result := CompareTermSlice(tc.a, tc.b); result != tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", tc.exp, tc.a, tc.b, result)
+		}
+		if result := EqualTermSlice(tc.a, tc.b); result != (tc.exp == 0) {
+			t.Errorf("expected equal to be %v for %v and %v but got %v", tc.exp == 0, tc.a, tc.b, result)
+		}
+	}
+}
+
+func TestValueLessGreater(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  int
+	}{
+		{`null`, `false`, -1},
+		{`1`, `"a"`, -1},
+		{`{"a": 1}`, `[1]`, 1},
+		{`2`, `10`, -1},
+		{`1.0`, `1`, 0},
+		{`[1, 2]`, `[1, 1, 1]`, 1},
+		{`x`, `y`, -1},
+	}
+
+	for _, tc := range tests {
+		a, b := MustParseTerm(tc.a).Value, MustParseTerm(tc.b).Value
+		if result := ValueLess(a, b); result != (tc.exp < 0) {
+			t.Errorf("expected ValueLess(%v, %v) to be %v", a, b, tc.exp < 0)
+		}
+		if result := ValueGreater(a, b); result != (tc.exp > 0) {
+			t.Errorf("expected ValueGreater(%v, %v) to be %v", a, b, tc.exp > 0)
+		}
+		if result := ValueLess(b, a); result != (tc.exp > 0) {
+			t.Errorf("expected ValueLess(%v, %v) to be %v", b, a, tc.exp > 0)
+		}
+	}
+}
+
+func BenchmarkCompareMixedArityCalls(b *testing.B) {
+	var calls []Call
+	for i := range 200 {
+		terms := []*Term{RefTerm(VarTerm("data"), StringTerm("fns"), StringTerm("f"+strconv.Itoa(i%7)))}
+		for j := range i % 5 {
+			terms = append(terms, RefTerm(VarTerm("input"), StringTerm("x"), IntNumberTerm(j)))
+		}
+		calls = append(calls, Call(terms))
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		for _, x := range calls {
+			for _, y := range calls {
+				Compare(x, y)
+			}
+		}
+	}
+}
+
+func TestCompareCallArity(t *testing.T) {
+	// Calls are parsed as expressions when they appear on their own.
+	call := func(s string) Call {
+		return Call(MustParseBody(s)[0].Terms.([]*Term))
+	}
+
+	tests := []struct {
+		a, b Call
+		exp  int
+	}{
+		{call(`g(1)`), call(`f(1, 1)`), -1},
+		{call(`f(1, 1)`), call(`g(1, 1)`), -1},
+		{call(`f(1, 2)`), call(`f(1, 1)`), 1},
+		{call(`f(1)`), call(`f(1)`), 0},
+		{call(`f()`), call(`a(1)`), -1},
+	}
+
+	for _, tc := range tests {
+		if result := Compare(tc.a, tc.b); result != tc.exp {
+			t.Errorf("expected %d for %v and %v but got %d", tc.exp, tc.a, tc.b, result)
+		}
+		if result := Compare(Args(tc.a[1:]), Args(tc.b[1:])); tc.exp != 0 && len(tc.a) != len(tc.b) && result != tc.exp {
+			t.Errorf("expected %d for args of %v and %v but got %d", tc.exp, tc.a, tc.b, result)
+		}
+	}
+}
+
+// BenchmarkCompareWorkloads compares pairs of equal but separately constructed
+// values, so that Compare has to visit them completely, except for
+// type_mismatch.
+func BenchmarkCompareWorkloads(b *testing.B) {
+	deepArray := func(depth int) Value {
+		v := Value(NewArray(IntNumberTerm(0)))
+		for i := range depth {
+			v = NewArray(IntNumberTerm(i), NewTerm(v))
+		}
+		return v
+	}
+	wideObject := func(n int) Value {
+		obj := NewObject()
+		for i := range n {
+			obj.Insert(StringTerm("key"+strconv.Itoa(i)), IntNumberTerm(i))
+		}
+		return obj
+	}
+	largeSet := func(n int) Value {
+		set := NewSet()
+		for i := range n {
+			set.Add(StringTerm("elem" + strconv.Itoa(i)))
+		}
+		return set
+	}
+	mixed := func(n int) Value {
+		rng := rand.New(rand.NewSource(1))
+		elems := make([]*Term, n)
+		for i := range elems {
+			elems[i] = NewTerm(GenRandomValue(rng, 3))
+		}
+		return NewArray(elems...)
+	}
+	call := func(s string) Value {
+		return Call(MustParseBody(s)[0].Terms.([]*Term))
+	}
+
+	tests := []struct {
+		note string
+		a, b Value
+	}{
+		{"null", Null{}, Null{}},
+		{"boolean", Boolean(true), Boolean(true)},
+		{"number/int", Number("12345"), Number("12345")},
+		{"number/decimal", Number("123.45"), Number("12345e-2")},
+		{"string", String("some string value"), String("some string value")},
+		{"var", Var("some_var"), Var("some_var")},
+		{"ref", MustParseRef("data.a.b[x].c"), MustParseRef("data.a.b[x].c")},
+		{"array", MustParseTerm(`[1, "a", true, null]`).Value, MustParseTerm(`[1, "a", true, null]`).Value},
+		{"object", MustParseTerm(`{"a": 1, "b": [2], "c": {"d": 3}}`).Value, MustParseTerm(`{"c": {"d": 3}, "b": [2], "a": 1}`).Value},
+		{"set", MustParseTerm(`{1, "a", [2], {"b": 3}}`).Value, MustParseTerm(`{{"b": 3}, [2], "a", 1}`).Value},
+		{"comprehension", MustParseTerm(`[x | x := input[_]; x > 1]`).Value, MustParseTerm(`[x | x := input[_]; x > 1]`).Value},
+		{"call", call(`f(x, 1, "a")`), call(`f(x, 1, "a")`)},
+		{"type_mismatch", String("a"), Number("1")},
+		{"deep_arrays", deepArray(100), deepArray(100)},
+		{"wide_objects", wideObject(1000), wideObject(1000)},
+		{"large_sets", largeSet(10000), largeSet(10000)},
+		{"mixed_types", mixed(1000), mixed(1000)},
+	}
+
+	for _, tc := range tests {
+		b.Run(tc.note, func(b *testing.B) {
+			b.ResetTimer()
+			for range b.N {
+				Compare(tc.a, tc.b)
+			}
+		})
+	}
+}
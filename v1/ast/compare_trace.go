@@ -0,0 +1,142 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "fmt"
+
+// CompareTrace compares a and b like Compare, and also returns a human
+// readable trace of the first difference between them, which decides the
+// result. The trace lists the steps into composite values that lead to the
+// difference, followed by the difference itself, e.g.
+//
+//	object key "x"
+//	number 1 < number 2
+//
+// The trace is empty if a and b are equal. It is only built once a and b
+// are known to differ, by following the path that Compare takes through them,
+// so equal values cost no more than with Compare.
+func CompareTrace(a, b any) (int, []string) {
+	c := Compare(a, b)
+	if c == 0 {
+		return 0, nil
+	}
+	var trace []string
+	traceCompare(a, b, c, &trace)
+	return c, trace
+}
+
+// traceCompare appends the trace of the first difference between a and b to
+// trace. c is the result of comparing a and b, which must not be zero.
+func traceCompare(a, b any, c int, trace *[]string) {
+	if t, ok := a.(*Term); ok {
+		if t == nil {
+			a = nil
+		} else {
+			a = t.Value
+		}
+	}
+	if t, ok := b.(*Term); ok {
+		if t == nil {
+			b = nil
+		} else {
+			b = t.Value
+		}
+	}
+	if a == nil || b == nil || sortOrder(a) != sortOrder(b) {
+		*trace = append(*trace, traceDifference(a, b, c))
+		return
+	}
+
+	switch a := a.(type) {
+	case Ref:
+		traceTermSlices("ref", a, b.(Ref), c, trace)
+		return
+	case *Array:
+		traceTermSlices("array", a.elems, b.(*Array).elems, c, trace)
+		return
+	case Call:
+		b := b.(Call)
+		if len(a) == len(b) {
+			traceTermSlices("call", a, b, c, trace)
+			return
+		}
+		*trace = append(*trace, traceLengths("call", len(a), len(b), c))
+		return
+	case Object:
+		traceObjects(a, b.(Object), c, trace)
+		return
+	case Set:
+		traceSets(a, b.(Set), c, trace)
+		return
+	}
+	*trace = append(*trace, traceDifference(a, b, c))
+}
+
+func traceTermSlices(kind string, a, b []*Term, c int, trace *[]string) {
+	for i := range min(len(a), len(b)) {
+		if c := compare(a[i], b[i]); c != 0 {
+			*trace = append(*trace, fmt.Sprintf("%s index %d", kind, i))
+			traceCompare(a[i], b[i], c, trace)
+			return
+		}
+	}
+	*trace = append(*trace, traceLengths(kind, len(a), len(b), c))
+}
+
+func traceObjects(a, b Object, c int, trace *[]string) {
+	akeys, bkeys := a.Keys(), b.Keys()
+	for i := range min(len(akeys), len(bkeys)) {
+		if c := compare(akeys[i], bkeys[i]); c != 0 {
+			*trace = append(*trace, fmt.Sprintf("object key %v %s object key %v", akeys[i], traceOp(c), bkeys[i]))
+			return
+		}
+		x, y := a.Get(akeys[i]), b.Get(bkeys[i])
+		if c := compare(x, y); c != 0 {
+			*trace = append(*trace, fmt.Sprintf("object key %v", akeys[i]))
+			traceCompare(x, y, c, trace)
+			return
+		}
+	}
+	*trace = append(*trace, traceLengths("object", len(akeys), len(bkeys), c))
+}
+
+// traceSets traces the first difference between the sorted elements of a and
+// b, which is an element that is only present in one of them.
+func traceSets(a, b Set, c int, trace *[]string) {
+	as, bs := a.Slice(), b.Slice()
+	for i := range min(len(as), len(bs)) {
+		if c := compare(as[i], bs[i]); c != 0 {
+			*trace = append(*trace, fmt.Sprintf("set element %d", i))
+			traceCompare(as[i], bs[i], c, trace)
+			return
+		}
+	}
+	*trace = append(*trace, traceLengths("set", len(as), len(bs), c))
+}
+
+func traceDifference(a, b any, c int) string {
+	return fmt.Sprintf("%s %s %s", traceDescribe(a), traceOp(c), traceDescribe(b))
+}
+
+func traceDescribe(x any) string {
+	switch x := x.(type) {
+	case nil:
+		return "nil"
+	case Value:
+		return fmt.Sprintf("%s %v", ValueName(x), x)
+	}
+	return fmt.Sprintf("%s %v", TypeName(x), x)
+}
+
+func traceLengths(kind string, a, b, c int) string {
+	return fmt.Sprintf("%s of length %d %s %s of length %d", kind, a, traceOp(c), kind, b)
+}
+
+func traceOp(c int) string {
+	if c < 0 {
+		return "<"
+	}
+	return ">"
+}
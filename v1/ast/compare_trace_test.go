@@ -0,0 +1,133 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestCompareTrace(t *testing.T) {
+	tests := []struct {
+		note  string
+		a, b  any
+		exp   int
+		trace []string
+	}{
+		{
+			note:  "object value",
+			a:     MustParseTerm(`{"x": 1}`),
+			b:     MustParseTerm(`{"x": 2}`),
+			exp:   -1,
+			trace: []string{`object key "x"`, `number 1 < number 2`},
+		},
+		{
+			note:  "nested",
+			a:     MustParseTerm(`{"a": [1, {"b": "y"}]}`),
+			b:     MustParseTerm(`{"a": [1.0, {"b": "x"}]}`),
+			exp:   1,
+			trace: []string{`object key "a"`, `array index 1`, `object key "b"`, `string "y" > string "x"`},
+		},
+		{
+			note:  "object key",
+			a:     MustParseTerm(`{"a": 1, "c": 1}`),
+			b:     MustParseTerm(`{"b": 1, "c": 1}`),
+			exp:   -1,
+			trace: []string{`object key "a" < object key "b"`},
+		},
+		{
+			note:  "object length",
+			a:     MustParseTerm(`{"a": 1, "b": 2}`),
+			b:     MustParseTerm(`{"a": 1}`),
+			exp:   1,
+			trace: []string{`object of length 2 > object of length 1`},
+		},
+		{
+			note:  "set element",
+			a:     MustParseTerm(`{1, 2, 3}`),
+			b:     MustParseTerm(`{1, 2, 4}`),
+			exp:   -1,
+			trace: []string{`set element 2`, `number 3 < number 4`},
+		},
+		{
+			note:  "array length",
+			a:     MustParseTerm(`[1]`),
+			b:     MustParseTerm(`[1, 2]`),
+			exp:   -1,
+			trace: []string{`array of length 1 < array of length 2`},
+		},
+		{
+			note:  "types",
+			a:     MustParseTerm(`1`),
+			b:     MustParseTerm(`"1"`),
+			exp:   -1,
+			trace: []string{`number 1 < string "1"`},
+		},
+		{
+			note:  "ref",
+			a:     MustParseRef(`data.a[x].c`),
+			b:     MustParseRef(`data.a[x].b`),
+			exp:   1,
+			trace: []string{`ref index 3`, `string "c" > string "b"`},
+		},
+		{
+			note:  "call arity",
+			a:     CallTerm(VarTerm("f"), IntNumberTerm(1)),
+			b:     CallTerm(VarTerm("f"), IntNumberTerm(1), IntNumberTerm(2)),
+			exp:   -1,
+			trace: []string{`call of length 2 < call of length 3`},
+		},
+		{
+			note:  "nil",
+			a:     nil,
+			b:     NullTerm(),
+			exp:   -1,
+			trace: []string{`nil < null null`},
+		},
+		{
+			note:  "body",
+			a:     MustParseBody(`x = 1`),
+			b:     MustParseBody(`x = 2`),
+			exp:   -1,
+			trace: []string{`body x = 1 < body x = 2`},
+		},
+		{
+			note: "equal",
+			a:    MustParseTerm(`{"a": [1, 2.0]}`),
+			b:    MustParseTerm(`{"a": [1.0, 2]}`),
+			exp:  0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			c, trace := CompareTrace(tc.a, tc.b)
+			if c != tc.exp {
+				t.Errorf("Expected %d but got %d", tc.exp, c)
+			}
+			if !slices.Equal(trace, tc.trace) {
+				t.Errorf("Expected trace %q but got %q", tc.trace, trace)
+			}
+		})
+	}
+}
+
+func TestCompareTraceMatchesCompare(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 1000 {
+		a, b := GenRandomValue(rng, 3), GenRandomValue(rng, 3)
+		if rng.Intn(2) == 0 {
+			b = respell(rng, a)
+		}
+		c, trace := CompareTrace(a, b)
+		if exp := Compare(a, b); c != exp {
+			t.Fatalf("Expected %d for %v and %v but got %d", exp, a, b, c)
+		}
+		if (c == 0) != (len(trace) == 0) {
+			t.Fatalf("Expected a trace only for unequal values, got %q for %v and %v", trace, a, b)
+		}
+	}
+}
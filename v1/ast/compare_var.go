@@ -0,0 +1,86 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "cmp"
+
+// VarCompareWildcardLast compares variables like VarCompare, except that
+// wildcards sort after all other variables and are equal to each other. Both _
+// and the unique names that the parser generates for it (see Var.IsWildcard)
+// are wildcards, so results do not depend on the numbering of wildcards.
+// Variables generated by the compiler, see Var.IsGenerated, are compared by
+// name like any other variable.
+func VarCompareWildcardLast(a, b Var) int {
+	return varCompareWildcard(a, b, 1)
+}
+
+// VarCompareWildcardFirst is like VarCompareWildcardLast, but sorts wildcards
+// before all other variables.
+func VarCompareWildcardFirst(a, b Var) int {
+	return varCompareWildcard(a, b, -1)
+}
+
+// varCompareWildcard compares a and b with wildcards being equal to each other
+// and ordered relative to other variables by wildcard, which is 1 or -1.
+func varCompareWildcard(a, b Var, wildcard int) int {
+	wa := a == Wildcard.Value || a.IsWildcard()
+	wb := b == Wildcard.Value || b.IsWildcard()
+	switch {
+	case wa && wb:
+		return 0
+	case wa:
+		return wildcard
+	case wb:
+		return -wildcard
+	}
+	return VarCompare(a, b)
+}
+
+// VarCompareCanonical compares variables like VarCompare, except that
+// generated variables (see Var.IsGenerated) are compared by the position at
+// which they first occur rather than by name. All variables of two ASTs must be
+// compared in order of occurrence with the same VarCompareCanonical, e.g. while
+// walking two bodies in lockstep, so that bodies that only differ in the
+// indices of their generated variables compare equal. Generated variables sort
+// after all other variables.
+//
+// The zero value is ready to use.
+type VarCompareCanonical struct {
+	a, b map[Var]int
+}
+
+// Compare compares a and b. Generated variables that have not been seen before
+// on their side are assigned the next position.
+func (c *VarCompareCanonical) Compare(a, b Var) int {
+	ga, gb := a.IsGenerated(), b.IsGenerated()
+	var ia, ib int
+	if ga {
+		ia = varPosition(&c.a, a)
+	}
+	if gb {
+		ib = varPosition(&c.b, b)
+	}
+	switch {
+	case ga && gb:
+		return cmp.Compare(ia, ib)
+	case ga:
+		return 1
+	case gb:
+		return -1
+	}
+	return VarCompare(a, b)
+}
+
+func varPosition(positions *map[Var]int, v Var) int {
+	if *positions == nil {
+		*positions = map[Var]int{}
+	}
+	i, ok := (*positions)[v]
+	if !ok {
+		i = len(*positions)
+		(*positions)[v] = i
+	}
+	return i
+}
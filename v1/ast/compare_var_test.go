@@ -0,0 +1,87 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVarCompareWildcard(t *testing.T) {
+	// The parser generates unique names for wildcards.
+	terms := MustParseBody(`f(_, x, _, __local0__, a)`)[0].Terms.([]*Term)
+	w1, x, w2, local, a := terms[1].Value.(Var), terms[2].Value.(Var), terms[3].Value.(Var), terms[4].Value.(Var), terms[5].Value.(Var)
+	if !w1.IsWildcard() || !w2.IsWildcard() || w1 == w2 {
+		t.Fatalf("Expected distinct wildcards but got %v and %v", w1, w2)
+	}
+	underscore := Wildcard.Value.(Var)
+
+	tests := []struct {
+		a, b        Var
+		last, first int
+	}{
+		{w1, w2, 0, 0},
+		{w1, underscore, 0, 0},
+		{w1, x, 1, -1},
+		{x, w2, -1, 1},
+		{underscore, a, 1, -1},
+		{w2, local, 1, -1},
+		{local, a, -1, -1},
+		{a, x, -1, -1},
+		{x, x, 0, 0},
+	}
+	for _, tc := range tests {
+		if act := VarCompareWildcardLast(tc.a, tc.b); act != tc.last {
+			t.Errorf("Expected VarCompareWildcardLast(%q, %q) to be %d but got %d", tc.a, tc.b, tc.last, act)
+		}
+		if act := VarCompareWildcardLast(tc.b, tc.a); act != -tc.last {
+			t.Errorf("Expected VarCompareWildcardLast(%q, %q) to be %d but got %d", tc.b, tc.a, -tc.last, act)
+		}
+		if act := VarCompareWildcardFirst(tc.a, tc.b); act != tc.first {
+			t.Errorf("Expected VarCompareWildcardFirst(%q, %q) to be %d but got %d", tc.a, tc.b, tc.first, act)
+		}
+	}
+
+	vars := []Var{w2, x, underscore, local, w1, a}
+	slices.SortStableFunc(vars, VarCompareWildcardLast)
+	if exp := []Var{local, a, x, w2, underscore, w1}; !slices.Equal(vars, exp) {
+		t.Fatalf("Expected %q but got %q", exp, vars)
+	}
+}
+
+func TestVarCompareCanonical(t *testing.T) {
+	vars := func(s string) []Var {
+		var vs []Var
+		WalkVars(MustParseBody(s), func(v Var) bool {
+			vs = append(vs, v)
+			return false
+		})
+		return vs
+	}
+	compare := func(a, b string) int {
+		var c VarCompareCanonical
+		return slices.CompareFunc(vars(a), vars(b), c.Compare)
+	}
+
+	tests := []struct {
+		a, b string
+		exp  int
+	}{
+		{`__local0__ = x; __local1__ = __local0__`, `__local4__ = x; __local2__ = __local4__`, 0},
+		{`__local0__ = x; __local1__ = __local0__`, `__local4__ = x; __local2__ = __local2__`, -1},
+		{`__local0__ = x; __local0__ = __local1__`, `__local4__ = x; __local2__ = __local4__`, -1},
+		{`__local0__ = x`, `__local0__ = y`, -1},
+		{`__local0__ = x`, `y = x`, 1},
+		{`x = __local3__`, `x = x`, 1},
+	}
+	for _, tc := range tests {
+		if act := compare(tc.a, tc.b); act != tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", tc.exp, tc.a, tc.b, act)
+		}
+		if act := compare(tc.b, tc.a); act != -tc.exp {
+			t.Errorf("Expected %d for %v and %v but got %d", -tc.exp, tc.b, tc.a, act)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+// CompareWalk compares a and b like Compare and calls visit for every node
+// that differs between them, starting with a and b themselves. If visit
+// returns false, the children of that node are not visited.
+//
+// The path passed to visit identifies the node relative to a and b:
+//
+//   - modules: "package", "imports" and index, "annotations" and index, or
+//     "rules" and index
+//   - rules: "head", "body", or "else"
+//   - bodies: the index of the expression
+//   - expressions: "terms" (followed by the operand index for calls), or
+//     "with" and index. Expressions whose terms are of different kinds, e.g.
+//     x and x == 1, have no children.
+//   - arrays, refs and calls: the index of the element
+//   - objects: the key
+//
+// Nodes of other types, such as heads, sets and comprehensions, are compared
+// with Compare as a whole and have no children. If a node only exists on one
+// side, e.g. a rule that was added, the other side is passed as nil.
+func CompareWalk(a, b any, visit func(path Ref, a, b any) bool) int {
+	c := Compare(a, b)
+	if c != 0 {
+		compareWalk(Ref{}, a, b, visit)
+	}
+	return c
+}
+
+func compareWalk(path Ref, a, b any, visit func(Ref, any, any) bool) {
+	if Compare(a, b) == 0 || !visit(path, a, b) {
+		return
+	}
+
+	if t, ok := a.(*Term); ok && t != nil {
+		a = t.Value
+	}
+	if t, ok := b.(*Term); ok && t != nil {
+		b = t.Value
+	}
+
+	switch x := a.(type) {
+	case *Module:
+		y, ok := b.(*Module)
+		if !ok || x == nil || y == nil {
+			return
+		}
+		compareWalk(path.Append(StringTerm("package")), orNil(x.Package), orNil(y.Package), visit)
+		compareWalkSlice(path.Append(StringTerm("imports")), x.Imports, y.Imports, visit)
+		compareWalkSlice(path.Append(StringTerm("annotations")), x.Annotations, y.Annotations, visit)
+		compareWalkSlice(path.Append(StringTerm("rules")), x.Rules, y.Rules, visit)
+	case *Rule:
+		y, ok := b.(*Rule)
+		if !ok || x == nil || y == nil {
+			return
+		}
+		compareWalk(path.Append(StringTerm("head")), orNil(x.Head), orNil(y.Head), visit)
+		compareWalk(path.Append(StringTerm("body")), x.Body, y.Body, visit)
+		compareWalk(path.Append(StringTerm("else")), orNil(x.Else), orNil(y.Else), visit)
+	case Body:
+		if y, ok := b.(Body); ok {
+			compareWalkSlice(path, x, y, visit)
+		}
+	case *Expr:
+		y, ok := b.(*Expr)
+		if !ok || x == nil || y == nil {
+			return
+		}
+		// Expressions whose terms are of different kinds, e.g. x and x == 1,
+		// differ as a whole and have no children.
+		xt, yt, ok := exprTermsValues(x, y)
+		if !ok {
+			return
+		}
+		compareWalk(path.Append(StringTerm("terms")), xt, yt, visit)
+		compareWalkSlice(path.Append(StringTerm("with")), x.With, y.With, visit)
+	case *Array:
+		if y, ok := b.(*Array); ok {
+			compareWalkSlice(path, x.elems, y.elems, visit)
+		}
+	case Ref:
+		if y, ok := b.(Ref); ok {
+			compareWalkSlice(path, x, y, visit)
+		}
+	case Call:
+		if y, ok := b.(Call); ok {
+			compareWalkSlice(path, x, y, visit)
+		}
+	case Object:
+		y, ok := b.(Object)
+		if !ok {
+			return
+		}
+		for _, k := range x.Keys() {
+			compareWalk(path.Append(k), x.Get(k), orNil(y.Get(k)), visit)
+		}
+		for _, k := range y.Keys() {
+			if x.Get(k) == nil {
+				compareWalk(path.Append(k), nil, y.Get(k), visit)
+			}
+		}
+	}
+}
+
+// exprTermsValues returns the terms of x and y as values that Compare accepts,
+// wrapping the operands of calls in a Call. It returns false if the terms of x
+// and y are of different kinds.
+func exprTermsValues(x, y *Expr) (any, any, bool) {
+	switch xt := x.Terms.(type) {
+	case []*Term:
+		if yt, ok := y.Terms.([]*Term); ok {
+			return Call(xt), Call(yt), true
+		}
+	case *Term:
+		if yt, ok := y.Terms.(*Term); ok {
+			return xt, yt, true
+		}
+	case *SomeDecl:
+		if yt, ok := y.Terms.(*SomeDecl); ok {
+			return xt, yt, true
+		}
+	case *Every:
+		if yt, ok := y.Terms.(*Every); ok {
+			return xt, yt, true
+		}
+	}
+	return nil, nil, false
+}
+
+// compareWalkSlice walks the elements of a and b at the same index, passing
+// nil for elements that only exist on one side.
+func compareWalkSlice[T comparable](path Ref, a, b []T, visit func(Ref, any, any) bool) {
+	for i := range max(len(a), len(b)) {
+		var x, y any
+		if i < len(a) {
+			x = orNil(a[i])
+		}
+		if i < len(b) {
+			y = orNil(b[i])
+		}
+		compareWalk(path.Append(InternedIntNumberTerm(i)), x, y, visit)
+	}
+}
+
+// orNil returns an untyped nil for nil pointers, so that visit can check for
+// missing nodes by comparing them to nil.
+func orNil[T comparable](v T) any {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,131 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompareWalk(t *testing.T) {
+	a := MustParseModule(`package test
+
+p if { input.x == 1 }
+
+q := {"a": [1, 2], "b": 3}
+
+r if { false }
+`)
+	b := MustParseModule(`package test
+
+p if { input.x == 2 }
+
+q := {"a": [1, 2, 4], "c": 3}
+
+r if { false }
+
+s := 1
+`)
+
+	var paths []string
+	result := CompareWalk(a, b, func(path Ref, _, _ any) bool {
+		paths = append(paths, path.String())
+		return true
+	})
+	if result != Compare(a, b) {
+		t.Fatalf("expected %d but got %d", Compare(a, b), result)
+	}
+
+	exp := []string{
+		``,
+		`"rules"[0]`,
+		`"rules"[0].body`,
+		`"rules"[0].body[0]`,
+		`"rules"[0].body[0].terms`,
+		`"rules"[0].body[0].terms[2]`,
+		`"rules"[1]`,
+		`"rules"[1].head`,
+		`"rules"[3]`,
+	}
+	if !slices.Equal(paths, exp) {
+		t.Fatalf("expected paths:\n%v\n\nbut got:\n%v", exp, paths)
+	}
+}
+
+func TestCompareWalkValues(t *testing.T) {
+	a := MustParseTerm(`{"a": [1, 2], "b": 3, "c": {1}}`)
+	b := MustParseTerm(`{"a": [1, 2, 4], "c": {2}, "d": 3}`)
+
+	type visited struct {
+		path string
+		a, b any
+	}
+	var result []visited
+	CompareWalk(a, b, func(path Ref, x, y any) bool {
+		result = append(result, visited{path.String(), x, y})
+		return true
+	})
+
+	exp := []visited{
+		{``, a.Value, b.Value},
+		{`"a"`, a.Value.(Object).Get(StringTerm("a")), b.Value.(Object).Get(StringTerm("a"))},
+		{`"a"[2]`, nil, IntNumberTerm(4)},
+		{`"b"`, IntNumberTerm(3), nil},
+		{`"c"`, MustParseTerm(`{1}`), MustParseTerm(`{2}`)},
+		{`"d"`, nil, IntNumberTerm(3)},
+	}
+	if len(result) != len(exp) {
+		t.Fatalf("expected %v but got %v", exp, result)
+	}
+	for i := range exp {
+		if result[i].path != exp[i].path || Compare(result[i].a, exp[i].a) != 0 || Compare(result[i].b, exp[i].b) != 0 {
+			t.Fatalf("expected %v but got %v", exp[i], result[i])
+		}
+		if (exp[i].a == nil) != (result[i].a == nil) || (exp[i].b == nil) != (result[i].b == nil) {
+			t.Fatalf("expected missing nodes to be nil: %v", result[i])
+		}
+	}
+}
+
+func TestCompareWalkExprTermsKinds(t *testing.T) {
+	a, b := MustParseExpr(`x`), MustParseExpr(`x == 1`)
+
+	var paths []string
+	result := CompareWalk(a, b, func(path Ref, _, _ any) bool {
+		paths = append(paths, path.String())
+		return true
+	})
+	if result != Compare(a, b) {
+		t.Fatalf("expected %d but got %d", Compare(a, b), result)
+	}
+	if exp := []string{``}; !slices.Equal(paths, exp) {
+		t.Fatalf("expected %v but got %v", exp, paths)
+	}
+}
+
+func TestCompareWalkPrune(t *testing.T) {
+	a := MustParseModule("package test\n\np if { input.x == 1 }\n\nq := 1")
+	b := MustParseModule("package test\n\np if { input.x == 2 }\n\nq := 2")
+
+	var paths []string
+	CompareWalk(a, b, func(path Ref, _, _ any) bool {
+		paths = append(paths, path.String())
+		return len(path) < 2
+	})
+
+	// The module itself is visited with an empty path, and each differing rule
+	// is visited without descending further.
+	exp := []string{``, `"rules"[0]`, `"rules"[1]`}
+	if !slices.Equal(paths, exp) {
+		t.Fatalf("expected %v but got %v", exp, paths)
+	}
+
+	if CompareWalk(a, a.Copy(), func(Ref, any, any) bool {
+		t.Fatal("unexpected visit for equal modules")
+		return true
+	}) != 0 {
+		t.Fatal("expected equal modules")
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "slices"
+
+// Difference describes a single difference between two values, as reported
+// by ValueDiff.
+type Difference struct {
+	// Path is the sequence of object keys, array indices and set elements
+	// leading to the difference. It is empty if the values differ at the root.
+	Path Ref
+	// Left is the value on the left-hand side, or nil if the value was added.
+	Left Value
+	// Right is the value on the right-hand side, or nil if the value was
+	// removed.
+	Right Value
+}
+
+// ValueDiff returns the differences between a and b, sorted by path.
+//
+// Objects are compared key by key, reporting keys that were added or removed
+// and recursing into keys present on both sides. Arrays are compared index by
+// index, reporting trailing elements as added or removed. Sets report the
+// elements that are only present on one side, i.e. their symmetric
+// difference. Any other values are reported as a single difference if they do
+// not compare equal.
+func ValueDiff(a, b Value) []Difference {
+	var diffs []Difference
+	diffValues(Ref{}, a, b, &diffs)
+	slices.SortFunc(diffs, func(x, y Difference) int {
+		return RefCompare(x.Path, y.Path)
+	})
+	return diffs
+}
+
+func diffValues(path Ref, a, b Value, diffs *[]Difference) {
+	if Compare(a, b) == 0 {
+		return
+	}
+
+	switch a := a.(type) {
+	case Object:
+		if b, ok := b.(Object); ok {
+			diffObjects(path, a, b, diffs)
+			return
+		}
+	case *Array:
+		if b, ok := b.(*Array); ok {
+			diffArrays(path, a, b, diffs)
+			return
+		}
+	case Set:
+		if b, ok := b.(Set); ok {
+			diffSets(path, a, b, diffs)
+			return
+		}
+	}
+
+	*diffs = append(*diffs, Difference{Path: path, Left: a, Right: b})
+}
+
+func diffObjects(path Ref, a, b Object, diffs *[]Difference) {
+	a.Foreach(func(k, v *Term) {
+		if w := b.Get(k); w != nil {
+			diffValues(path.Append(k), v.Value, w.Value, diffs)
+		} else {
+			*diffs = append(*diffs, Difference{Path: path.Append(k), Left: v.Value})
+		}
+	})
+	b.Foreach(func(k, w *Term) {
+		if a.Get(k) == nil {
+			*diffs = append(*diffs, Difference{Path: path.Append(k), Right: w.Value})
+		}
+	})
+}
+
+func diffArrays(path Ref, a, b *Array, diffs *[]Difference) {
+	for i := range max(a.Len(), b.Len()) {
+		p := path.Append(InternedIntNumberTerm(i))
+		switch {
+		case i >= a.Len():
+			*diffs = append(*diffs, Difference{Path: p, Right: b.Elem(i).Value})
+		case i >= b.Len():
+			*diffs = append(*diffs, Difference{Path: p, Left: a.Elem(i).Value})
+		default:
+			diffValues(p, a.Elem(i).Value, b.Elem(i).Value, diffs)
+		}
+	}
+}
+
+func diffSets(path Ref, a, b Set, diffs *[]Difference) {
+	a.Foreach(func(x *Term) {
+		if !b.Contains(x) {
+			*diffs = append(*diffs, Difference{Path: path.Append(x), Left: x.Value})
+		}
+	})
+	b.Foreach(func(x *Term) {
+		if !a.Contains(x) {
+			*diffs = append(*diffs, Difference{Path: path.Append(x), Right: x.Value})
+		}
+	})
+}
+
+// FirstDifference returns the path to the first difference between a and b,
+// and whether they differ at all. Differences are visited in the order in
+// which Compare visits values, so the result is the place that decides
+// Compare(a, b).
+//
+// Paths follow the conventions of ValueDiff: object keys and set elements
+// present on only one side, and array indices past the end of the shorter
+// array, are reported as the last path element. Unlike ValueDiff, the
+// traversal stops at the first difference, which makes FirstDifference much
+// cheaper for large values.
+func FirstDifference(a, b Value) (Ref, bool) {
+	path, ok := firstDifference(a, b)
+	if !ok {
+		return nil, false
+	}
+	slices.Reverse(path)
+	return path, true
+}
+
+// firstDifference returns the path to the first difference in reverse, so
+// that it is only built once a difference has been found.
+func firstDifference(a, b Value) (Ref, bool) {
+	switch a := a.(type) {
+	case Object:
+		if b, ok := b.(Object); ok {
+			return firstObjectDifference(a, b)
+		}
+	case *Array:
+		if b, ok := b.(*Array); ok {
+			return firstArrayDifference(a, b)
+		}
+	case Set:
+		if b, ok := b.(Set); ok {
+			return firstSetDifference(a, b)
+		}
+	}
+
+	if Compare(a, b) != 0 {
+		return Ref{}, true
+	}
+	return nil, false
+}
+
+func firstObjectDifference(a, b Object) (Ref, bool) {
+	akeys, bkeys := a.Keys(), b.Keys()
+	for i := range min(len(akeys), len(bkeys)) {
+		if c := Compare(akeys[i], bkeys[i]); c < 0 {
+			return Ref{akeys[i]}, true
+		} else if c > 0 {
+			return Ref{bkeys[i]}, true
+		}
+		if path, ok := firstDifference(a.Get(akeys[i]).Value, b.Get(bkeys[i]).Value); ok {
+			return append(path, akeys[i]), true
+		}
+	}
+	switch {
+	case len(akeys) < len(bkeys):
+		return Ref{bkeys[len(akeys)]}, true
+	case len(akeys) > len(bkeys):
+		return Ref{akeys[len(bkeys)]}, true
+	}
+	return nil, false
+}
+
+func firstArrayDifference(a, b *Array) (Ref, bool) {
+	for i := range min(a.Len(), b.Len()) {
+		if path, ok := firstDifference(a.Elem(i).Value, b.Elem(i).Value); ok {
+			return append(path, InternedIntNumberTerm(i)), true
+		}
+	}
+	if a.Len() != b.Len() {
+		return Ref{InternedIntNumberTerm(min(a.Len(), b.Len()))}, true
+	}
+	return nil, false
+}
+
+// firstSetDifference reports the first element, in sorted order, that is only
+// present in one of a and b. At the first index where the sorted elements
+// differ, the lesser element cannot occur in the other set.
+func firstSetDifference(a, b Set) (Ref, bool) {
+	as, bs := a.Slice(), b.Slice()
+	for i := range min(len(as), len(bs)) {
+		if c := Compare(as[i], bs[i]); c < 0 {
+			return Ref{as[i]}, true
+		} else if c > 0 {
+			return Ref{bs[i]}, true
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return Ref{bs[len(as)]}, true
+	case len(as) > len(bs):
+		return Ref{as[len(bs)]}, true
+	}
+	return nil, false
+}
+
+// ModuleDelta describes how the rules of two modules differ, as reported by
+// ModuleDiff.
+type ModuleDelta struct {
+	// Added contains the rules that are only present in the second module, in
+	// the order of that module.
+	Added []*Rule
+	// Removed contains the rules that are only present in the first module, in
+	// the order of that module.
+	Removed []*Rule
+	// Modified contains the rules that are present in both modules but differ,
+	// in the order of the first module.
+	Modified []RuleDelta
+}
+
+// RuleDelta is a rule that was modified between two modules.
+type RuleDelta struct {
+	Old *Rule
+	New *Rule
+}
+
+// ModuleDiff returns the rules that were added, removed or modified between
+// a and b. Rules are matched by their head refs rather than their positions,
+// so reordering rules is not reported as a change. A nil module has no rules.
+//
+// Rules with the same ref, such as incremental rules and function overloads,
+// are matched in two passes: rules that compare equal are matched first, so
+// reordering them is not reported either. The remaining rules with the same
+// ref are then matched by their order and reported as modified if they are
+// not equal, and any left over as added or removed.
+func ModuleDiff(a, b *Module) ModuleDelta {
+	var as, bs []*Rule
+	if a != nil {
+		as = a.Rules
+	}
+	if b != nil {
+		bs = b.Rules
+	}
+
+	// match[i] is the index of the rule in bs matched with as[i], or -1, and
+	// equal[i] is true if both rules are equal.
+	match := make([]int, len(as))
+	equal := make([]bool, len(as))
+	matched := make([]bool, len(bs))
+	groups := map[string][]int{}
+	for j, r := range bs {
+		k := r.Head.Ref().String()
+		groups[k] = append(groups[k], j)
+	}
+	for i := range as {
+		match[i] = -1
+		for _, j := range groups[as[i].Head.Ref().String()] {
+			if !matched[j] && as[i].Compare(bs[j]) == 0 {
+				match[i], equal[i], matched[j] = j, true, true
+				break
+			}
+		}
+	}
+	for i := range as {
+		if match[i] >= 0 {
+			continue
+		}
+		for _, j := range groups[as[i].Head.Ref().String()] {
+			if !matched[j] {
+				match[i], matched[j] = j, true
+				break
+			}
+		}
+	}
+
+	var delta ModuleDelta
+	for i, j := range match {
+		switch {
+		case j < 0:
+			delta.Removed = append(delta.Removed, as[i])
+		case !equal[i]:
+			delta.Modified = append(delta.Modified, RuleDelta{Old: as[i], New: bs[j]})
+		}
+	}
+	for j, r := range bs {
+		if !matched[j] {
+			delta.Added = append(delta.Added, r)
+		}
+	}
+	return delta
+}
@@ -0,0 +1,281 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValueDiff(t *testing.T) {
+	tests := []struct {
+		note string
+		a, b string
+		exp  []string
+	}{
+		{
+			note: "equal",
+			a:    `{"a": [1, {2}], "b": null}`,
+			b:    `{"b": null, "a": [1.0, {2}]}`,
+		},
+		{
+			note: "scalars",
+			a:    `1`,
+			b:    `"1"`,
+			exp:  []string{`[]: 1 -> "1"`},
+		},
+		{
+			note: "object keys",
+			a:    `{"a": 1, "b": 2, "c": 3}`,
+			b:    `{"b": 2, "c": 4, "d": 5}`,
+			exp: []string{
+				`["a"]: 1 -> <nil>`,
+				`["c"]: 3 -> 4`,
+				`["d"]: <nil> -> 5`,
+			},
+		},
+		{
+			note: "array indices",
+			a:    `[1, 2, 3]`,
+			b:    `[1, 5]`,
+			exp: []string{
+				`[1]: 2 -> 5`,
+				`[2]: 3 -> <nil>`,
+			},
+		},
+		{
+			note: "set members",
+			a:    `{1, 2, 3}`,
+			b:    `{2, 3, 4}`,
+			exp: []string{
+				`[1]: 1 -> <nil>`,
+				`[4]: <nil> -> 4`,
+			},
+		},
+		{
+			note: "nested",
+			a:    `{"a": {"b": [1, {"c": true}]}, "x": [1]}`,
+			b:    `{"a": {"b": [1, {"c": false}]}, "x": {1}}`,
+			exp: []string{
+				`["a", "b", 1, "c"]: true -> false`,
+				`["x"]: [1] -> {1}`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			diffs := ValueDiff(MustParseTerm(tc.a).Value, MustParseTerm(tc.b).Value)
+			act := make([]string, len(diffs))
+			for i, d := range diffs {
+				act[i] = fmt.Sprintf("%v: %v -> %v", NewArray(d.Path...), d.Left, d.Right)
+			}
+			if strings.Join(act, "\n") != strings.Join(tc.exp, "\n") {
+				t.Fatalf("Expected:\n%v\n\nGot:\n%v", strings.Join(tc.exp, "\n"), strings.Join(act, "\n"))
+			}
+		})
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	tests := []struct {
+		note string
+		a, b string
+		exp  string
+	}{
+		{
+			note: "equal",
+			a:    `{"a": [1, {2}], "b": null}`,
+			b:    `{"b": null, "a": [1.0, {2}]}`,
+		},
+		{
+			note: "scalars",
+			a:    `1`,
+			b:    `"1"`,
+			exp:  `[]`,
+		},
+		{
+			note: "deep object key",
+			a:    `{"a": {"b": {"c": {"d": 1, "e": 2}}}, "z": 1}`,
+			b:    `{"a": {"b": {"c": {"d": 1, "e": 3}}}, "z": 2}`,
+			exp:  `["a", "b", "c", "e"]`,
+		},
+		{
+			note: "missing object key",
+			a:    `{"a": 1, "c": 3}`,
+			b:    `{"a": 1, "b": 2, "c": 3}`,
+			exp:  `["b"]`,
+		},
+		{
+			note: "extra object key",
+			a:    `{"a": 1, "b": 2}`,
+			b:    `{"a": 1}`,
+			exp:  `["b"]`,
+		},
+		{
+			note: "array index",
+			a:    `[1, [2, 3, 4], 5]`,
+			b:    `[1, [2, 3, 5], 6]`,
+			exp:  `[1, 2]`,
+		},
+		{
+			note: "array length",
+			a:    `[1, 2]`,
+			b:    `[1, 2, 3]`,
+			exp:  `[2]`,
+		},
+		{
+			note: "set member",
+			a:    `{"x": {1, 3, 4}}`,
+			b:    `{"x": {1, 2, 3}}`,
+			exp:  `["x", 2]`,
+		},
+		{
+			note: "type mismatch",
+			a:    `{"x": [1]}`,
+			b:    `{"x": {1}}`,
+			exp:  `["x"]`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			a, b := MustParseTerm(tc.a).Value, MustParseTerm(tc.b).Value
+			path, ok := FirstDifference(a, b)
+			if ok != (tc.exp != "") {
+				t.Fatalf("Expected differ to be %v but got %v", tc.exp != "", ok)
+			}
+			if ok && NewArray(path...).String() != tc.exp {
+				t.Fatalf("Expected path %v but got %v", tc.exp, NewArray(path...))
+			}
+		})
+	}
+}
+
+func TestFirstDifferenceMatchesValueDiff(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 1000 {
+		a, b := GenRandomValue(rng, 3), GenRandomValue(rng, 3)
+		if rng.Intn(2) == 0 {
+			b = respell(rng, a)
+		}
+		path, ok := FirstDifference(a, b)
+		diffs := ValueDiff(a, b)
+		if ok != (len(diffs) > 0) {
+			t.Fatalf("Expected differ to be %v for %v and %v", len(diffs) > 0, a, b)
+		}
+		if ok && !slices.ContainsFunc(diffs, func(d Difference) bool { return d.Path.Equal(path) }) {
+			t.Fatalf("Expected path %v for %v and %v to be reported by ValueDiff", path, a, b)
+		}
+	}
+}
+
+func TestModuleDiff(t *testing.T) {
+	tests := []struct {
+		note     string
+		a, b     string
+		added    []string
+		removed  []string
+		modified []string
+	}{
+		{
+			note: "equal",
+			a:    "p := 1\nq := 2",
+			b:    "p := 1\nq := 2",
+		},
+		{
+			note: "reordered",
+			a:    "p := 1\nq := 2\nr := 3",
+			b:    "r := 3\np := 1\nq := 2",
+		},
+		{
+			note:    "added and removed",
+			a:       "p := 1\nq := 2",
+			b:       "q := 2\nr := 3\ns := 4",
+			added:   []string{"r := 3", "s := 4"},
+			removed: []string{"p := 1"},
+		},
+		{
+			note:     "modified",
+			a:        "p := 1\nq := 2\nr := 3",
+			b:        "r := 3\nq := 20\np := 1 if input.x",
+			modified: []string{"p := 1 -> p := 1 if input.x", "q := 2 -> q := 20"},
+		},
+		{
+			note: "reordered overloads",
+			a:    "f(1) := 1\nf(2) := 2\nf(x) := x",
+			b:    "f(x) := x\nf(2) := 2\nf(1) := 1",
+		},
+		{
+			note:     "modified overload",
+			a:        "f(1) := 1\nf(2) := 2\nf(x) := x",
+			b:        "f(x) := x\nf(2) := 3\nf(1) := 1",
+			modified: []string{"f(2) := 2 -> f(2) := 3"},
+		},
+		{
+			note:    "removed overload",
+			a:       "s contains 1\ns contains 2\ns contains 3",
+			b:       "s contains 3\ns contains 1",
+			removed: []string{"s contains 2"},
+		},
+		{
+			note:     "modified and added overloads",
+			a:        "s contains 1\ns contains 2",
+			b:        "s contains 3\ns contains 1\ns contains 4",
+			modified: []string{"s contains 2 -> s contains 3"},
+			added:    []string{"s contains 4"},
+		},
+		{
+			note:    "nested refs",
+			a:       "a.b.c := 1\na.b.d := 2",
+			b:       "a.b.d := 2\na.b.e := 1",
+			added:   []string{"a.b.e := 1"},
+			removed: []string{"a.b.c := 1"},
+		},
+	}
+
+	rules := func(rs []*Rule) []string {
+		var result []string
+		for _, r := range rs {
+			result = append(result, ruleSummary(r))
+		}
+		return result
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			a := MustParseModule("package test\n" + tc.a)
+			b := MustParseModule("package test\n" + tc.b)
+			delta := ModuleDiff(a, b)
+
+			var modified []string
+			for _, d := range delta.Modified {
+				modified = append(modified, ruleSummary(d.Old)+" -> "+ruleSummary(d.New))
+			}
+			if act := rules(delta.Added); !slices.Equal(act, tc.added) {
+				t.Errorf("Expected added %v but got %v", tc.added, act)
+			}
+			if act := rules(delta.Removed); !slices.Equal(act, tc.removed) {
+				t.Errorf("Expected removed %v but got %v", tc.removed, act)
+			}
+			if !slices.Equal(modified, tc.modified) {
+				t.Errorf("Expected modified %v but got %v", tc.modified, modified)
+			}
+		})
+	}
+
+	m := MustParseModule("package test\np := 1")
+	if delta := ModuleDiff(nil, m); len(delta.Added) != 1 || len(delta.Removed) != 0 {
+		t.Fatalf("Expected all rules to be added but got %v", delta)
+	}
+}
+
+// ruleSummary returns the source text of rule r.
+func ruleSummary(r *Rule) string {
+	return string(r.Location.Text)
+}
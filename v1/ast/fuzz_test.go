@@ -39,3 +39,55 @@ func FuzzCompileModulesWithPrintAndAllFutureKWs(f *testing.F) {
 		})
 	})
 }
+
+// FuzzCompareNumber checks that Compare is a total order on numbers: it is
+// reflexive, antisymmetric and transitive for every triple of numbers it
+// accepts. Numbers that CompareErr rejects are skipped. These include numbers
+// whose exponents exceed the range of big.Float, e.g. 1e9999999999 and
+// 1e-9999999999. Compare panics on them even though they are valid JSON.
+func FuzzCompareNumber(f *testing.F) {
+	seeds := []string{
+		"0", "-0", "0.0", "1", "1.0", "1e0", "10e-1", "-1", "1.5", "15e-1", "0.1", "1e-1",
+		"9223372036854775807", "9223372036854775808", "-9223372036854775809",
+		"123456789123456789123.5", "1e308", "1e309", "4.9e-324", "1e-400", "1e999999",
+		"Inf", "-Inf", "NaN", "1e9999999999", "1e-9999999999", "0e99999999999",
+	}
+	for i := range seeds {
+		f.Add(seeds[i], seeds[(i+1)%len(seeds)], seeds[(i+7)%len(seeds)])
+	}
+	f.Fuzz(func(t *testing.T, a, b, c string) {
+		nums := []Number{Number(a), Number(b), Number(c)}
+		for _, n := range nums {
+			if _, err := CompareErr(n, n); err != nil {
+				t.Skip()
+			}
+		}
+
+		cmp := func(x, y Number) int {
+			c, err := CompareErr(x, y)
+			if err != nil {
+				t.Fatalf("Expected no error for %v and %v but got %v", x, y, err)
+			}
+			return c
+		}
+
+		for _, x := range nums {
+			if c := cmp(x, x); c != 0 {
+				t.Fatalf("Expected %v to be equal to itself but got %d", x, c)
+			}
+			for _, y := range nums {
+				xy, yx := cmp(x, y), cmp(y, x)
+				if (xy < 0) != (yx > 0) || (xy == 0) != (yx == 0) {
+					t.Fatalf("Expected antisymmetry for %v and %v but got %d and %d", x, y, xy, yx)
+				}
+				for _, z := range nums {
+					if cmp(x, y) <= 0 && cmp(y, z) <= 0 {
+						if xz := cmp(x, z); xz > 0 || (xz == 0) != (cmp(x, y) == 0 && cmp(y, z) == 0) {
+							t.Fatalf("Expected transitivity for %v <= %v <= %v but got %d", x, y, z, xz)
+						}
+					}
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,196 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"cmp"
+	"strconv"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Hash returns a hash code for v that is consistent with Compare: if
+// Compare(a, b) == 0, then Hash(a) == Hash(b). Numbers are hashed by their
+// numeric value, so 1, 1.0 and 1e0 all hash the same, and the elements of
+// objects and sets are combined independently of their order.
+//
+// Unlike Value.Hash, the result only depends on the value itself, and not on
+// how it was constructed, so it is safe to use as a key in external caches.
+// Values of other types fall back to their Hash method.
+func Hash(v Value) uint64 {
+	return hashAny(v)
+}
+
+// CompareWithHashHint orders a and b by their hashes ha and hb, as returned by
+// Hash, and only compares them with Compare if the hashes are equal. Since
+// values that compare equal have equal hashes, this agrees with Compare on
+// which terms are equal, but orders unequal terms differently. It is cheaper
+// than Compare when hashes are computed once up front, e.g. to group equal
+// terms of a large collection.
+func CompareWithHashHint(a, b *Term, ha, hb uint64) int {
+	if ha != hb {
+		return cmp.Compare(ha, hb)
+	}
+	return Compare(a, b)
+}
+
+func hashAny(x any) uint64 {
+	switch x := x.(type) {
+	case nil:
+		return hashMix(0, 0)
+	case *Term:
+		if x == nil {
+			return hashAny(nil)
+		}
+		return hashAny(x.Value)
+	case Null:
+		return hashMix(hashTag(x), 0)
+	case Boolean:
+		if x {
+			return hashMix(hashTag(x), 1)
+		}
+		return hashMix(hashTag(x), 0)
+	case Number:
+		return hashString(hashTag(x), numberCanonical(x))
+	case String:
+		return hashString(hashTag(x), string(x))
+	case Var:
+		return hashString(hashTag(x), string(x))
+	case Ref:
+		return hashTerms(hashTag(x), x)
+	case *Array:
+		return hashTerms(hashTag(x), x.elems)
+	case Call:
+		return hashTerms(hashTag(x), x)
+	case Args:
+		return hashTerms(hashTag(x), x)
+	case Object:
+		var sum uint64
+		x.Foreach(func(k, v *Term) {
+			sum += hashFinalize(hashMix(hashAny(k), hashAny(v)))
+		})
+		return hashMix(hashMix(hashTag(x), uint64(x.Len())), sum)
+	case Set:
+		var sum uint64
+		x.Foreach(func(t *Term) {
+			sum += hashFinalize(hashAny(t))
+		})
+		return hashMix(hashMix(hashTag(x), uint64(x.Len())), sum)
+	case *ArrayComprehension:
+		h := hashMix(hashTag(x), hashAny(x.Term))
+		return hashMix(h, hashAny(x.Body))
+	case *ObjectComprehension:
+		h := hashMix(hashTag(x), hashAny(x.Key))
+		h = hashMix(h, hashAny(x.Value))
+		return hashMix(h, hashAny(x.Body))
+	case *SetComprehension:
+		h := hashMix(hashTag(x), hashAny(x.Term))
+		return hashMix(h, hashAny(x.Body))
+	case Body:
+		h := hashMix(hashTag(x), uint64(len(x)))
+		for _, expr := range x {
+			h = hashMix(h, hashAny(expr))
+		}
+		return h
+	case *Expr:
+		if x == nil {
+			return hashAny(nil)
+		}
+		h := hashMix(hashTag(x), uint64(x.sortOrder()))
+		h = hashMix(h, uint64(x.Index))
+		if x.Negated {
+			h = hashMix(h, 1)
+		}
+		switch t := x.Terms.(type) {
+		case *Term:
+			h = hashMix(h, hashAny(t))
+		case []*Term:
+			h = hashMix(h, hashTerms(0, t))
+		case *SomeDecl:
+			h = hashMix(h, hashAny(t))
+		case *Every:
+			h = hashMix(h, hashAny(t))
+		}
+		h = hashMix(h, uint64(len(x.With)))
+		for _, w := range x.With {
+			h = hashMix(h, hashAny(w))
+		}
+		return h
+	case *SomeDecl:
+		return hashTerms(hashTag(x), x.Symbols)
+	case *Every:
+		h := hashMix(hashTag(x), hashAny(x.Key))
+		h = hashMix(h, hashAny(x.Value))
+		h = hashMix(h, hashAny(x.Domain))
+		return hashMix(h, hashAny(x.Body))
+	case *With:
+		if x == nil {
+			return hashAny(nil)
+		}
+		h := hashMix(hashTag(x), hashAny(x.Target))
+		return hashMix(h, hashAny(x.Value))
+	case Value:
+		// Values defined outside of this package, e.g. OrderedValue
+		// implementations, are expected to hash consistently with their
+		// CompareValue method.
+		tag, _ := trySortOrder(x)
+		return hashMix(uint64(tag)+1, uint64(x.Hash()))
+	}
+	panic(&UnsupportedValueError{Value: x})
+}
+
+// numberCanonical returns a representation of n that is identical for all
+// numbers that compare equal.
+func numberCanonical(n Number) string {
+	if i, ok := n.Int64(); ok {
+		return strconv.FormatInt(i, 10)
+	}
+	if r, ok := nonFiniteRank(n); ok {
+		switch r {
+		case -1:
+			return "-Inf"
+		case 1:
+			return "+Inf"
+		default:
+			return "NaN"
+		}
+	}
+	if d, ok := hugeDecimal(n); ok {
+		return strconv.Itoa(d.sign) + "e" + strconv.FormatInt(d.exp, 10) + ":" + d.hi + d.lo
+	}
+	return numberRat(n).RatString()
+}
+
+func hashTag(x any) uint64 {
+	return uint64(sortOrder(x)) + 1
+}
+
+func hashString(h uint64, s string) uint64 {
+	return hashMix(h, xxhash.Sum64String(s))
+}
+
+func hashTerms(h uint64, ts []*Term) uint64 {
+	h = hashMix(h, uint64(len(ts)))
+	for _, t := range ts {
+		h = hashMix(h, hashAny(t))
+	}
+	return h
+}
+
+// hashMix combines h with x. The result depends on the order of the calls.
+func hashMix(h, x uint64) uint64 {
+	return hashFinalize(h ^ (x + 0x9e3779b97f4a7c15 + (h << 6) + (h >> 2)))
+}
+
+// hashFinalize scrambles the bits of h (splitmix64) so that hashes can be
+// summed without the result being dominated by their low bits.
+func hashFinalize(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
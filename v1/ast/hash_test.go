@@ -0,0 +1,176 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{`1`, `1.0`, true},
+		{`1`, `1e0`, true},
+		{`100`, `1E2`, true},
+		{`0.5`, `5e-1`, true},
+		{`123456789123456789123`, `123456789123456789123.0`, true},
+		{`1`, `2`, false},
+		{`1`, `"1"`, false},
+		{`null`, `false`, false},
+		{`"a"`, `"b"`, false},
+		{`[1, 2]`, `[1.0, 2.0]`, true},
+		{`[1, 2]`, `[2, 1]`, false},
+		{`[[1], 2]`, `[1, [2]]`, false},
+		{`{1, 2, 3}`, `{3, 1.0, 2}`, true},
+		{`{1, 2}`, `{1, 2, 3}`, false},
+		{`{"a": 1, "b": 2}`, `{"b": 2, "a": 1.0}`, true},
+		{`{"a": 1, "b": 2}`, `{"a": 2, "b": 1}`, false},
+		{`{"a": 1}`, `{"a", 1}`, false},
+		{`[x | x = 1]`, `[x | x = 1.0]`, true},
+		{`[x | x = 1]`, `{x | x = 1}`, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.a+"_"+tc.b, func(t *testing.T) {
+			a, b := MustParseTerm(tc.a).Value, MustParseTerm(tc.b).Value
+			if (Compare(a, b) == 0) != tc.equal {
+				t.Fatalf("Expected Compare(%v, %v) == 0 to be %v", a, b, tc.equal)
+			}
+			if act := Hash(a) == Hash(b); act != tc.equal {
+				t.Errorf("Expected Hash(%v) == Hash(%v) to be %v", a, b, tc.equal)
+			}
+		})
+	}
+}
+
+func TestHashLazyObject(t *testing.T) {
+	lazy := LazyObject(map[string]any{"a": 1, "b": []any{"c"}})
+	obj := MustParseTerm(`{"b": ["c"], "a": 1.0}`).Value
+	if Hash(lazy) != Hash(obj) {
+		t.Fatalf("Expected lazy and strict objects to hash the same")
+	}
+}
+
+func TestHashHugeExponent(t *testing.T) {
+	a, b, c := Number("1.5e999999"), Number("15e999998"), Number("1.5e999998")
+	if Compare(a, b) != 0 || Hash(a) != Hash(b) {
+		t.Fatalf("Expected %v and %v to compare and hash the same", a, b)
+	}
+	if Hash(a) == Hash(c) {
+		t.Fatalf("Expected %v and %v to hash differently", a, c)
+	}
+	if Hash(Number("1e999999999")) != Hash(Number("10e999999998")) {
+		t.Fatal("Expected equal numbers beyond the range of big.Rat to hash the same")
+	}
+}
+
+func TestHashConsistentWithCompare(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 1000 {
+		a := GenRandomValue(rng, 3)
+		b := respell(rng, a)
+		if Compare(a, b) != 0 {
+			t.Fatalf("Expected %v and %v to compare equal", a, b)
+		}
+		if Hash(a) != Hash(b) {
+			t.Fatalf("Expected Hash(%v) == Hash(%v)", a, b)
+		}
+
+		c := GenRandomValue(rng, 2)
+		if Compare(a, c) == 0 && Hash(a) != Hash(c) {
+			t.Fatalf("Expected Hash(%v) == Hash(%v)", a, c)
+		}
+	}
+}
+
+func TestSetHashOrderIndependent(t *testing.T) {
+	elems := []*Term{
+		IntNumberTerm(1),
+		StringTerm("a"),
+		ArrayTerm(IntNumberTerm(1), IntNumberTerm(2)),
+		SetTerm(StringTerm("x"), StringTerm("y")),
+		ObjectTerm(Item(StringTerm("k"), NullTerm())),
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	exp := NewSet(elems...)
+	for range 20 {
+		shuffled := slices.Clone(elems)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		s := NewSet()
+		for _, e := range shuffled {
+			s.Add(e)
+		}
+		if s.Hash() != exp.Hash() || Hash(s) != Hash(exp) {
+			t.Fatalf("expected equal hashes for %v and %v", s, exp)
+		}
+	}
+
+	// Element hashes with the same sum must not collide.
+	a, b := MustParseTerm(`{1, 4}`).Value, MustParseTerm(`{2, 3}`).Value
+	if a.Hash() == b.Hash() {
+		t.Fatalf("expected different hashes for %v and %v", a, b)
+	}
+
+	// Spot check that distinct small sets of integers rarely collide.
+	seen := map[int]Set{}
+	collisions := 0
+	for i := range 64 {
+		for j := i + 1; j < 64; j++ {
+			s := NewSet(IntNumberTerm(i), IntNumberTerm(j))
+			if _, ok := seen[s.Hash()]; ok {
+				collisions++
+			}
+			seen[s.Hash()] = s
+		}
+	}
+	if collisions > 0 {
+		t.Fatalf("expected no collisions but got %d", collisions)
+	}
+}
+
+func TestCompareWithHashHint(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	type hashed struct {
+		term *Term
+		hash uint64
+	}
+	terms := make([]hashed, 500)
+	for i := range terms {
+		v := GenRandomValue(rng, 1)
+		if i > 0 && rng.Intn(2) == 0 {
+			v = respell(rng, terms[rng.Intn(i)].term.Value)
+		}
+		terms[i] = hashed{NewTerm(v), Hash(v)}
+	}
+
+	for _, a := range terms[:50] {
+		for _, b := range terms {
+			act := CompareWithHashHint(a.term, b.term, a.hash, b.hash)
+			if (act == 0) != (Compare(a.term, b.term) == 0) {
+				t.Fatalf("expected CompareWithHashHint to agree with Compare on equality of %v and %v", a.term, b.term)
+			}
+			if rev := CompareWithHashHint(b.term, a.term, b.hash, a.hash); rev != -act {
+				t.Fatalf("expected antisymmetric result for %v and %v but got %d and %d", a.term, b.term, act, rev)
+			}
+		}
+	}
+
+	slices.SortFunc(terms, func(a, b hashed) int { return CompareWithHashHint(a.term, b.term, a.hash, b.hash) })
+	seen := map[uint64]bool{}
+	for i, x := range terms {
+		if i > 0 && x.hash == terms[i-1].hash {
+			continue
+		}
+		if seen[x.hash] {
+			t.Fatalf("expected terms with equal hashes to be adjacent, found %v twice", x.term)
+		}
+		seen[x.hash] = true
+	}
+}
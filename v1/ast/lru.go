@@ -0,0 +1,63 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "container/list"
+
+// lruCache is a map of bounded size that evicts the least recently used
+// entries once full. It is not safe for concurrent use.
+type lruCache[K comparable, V any] struct {
+	size    int
+	entries map[K]*list.Element
+	order   *list.List
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// newLRUCache returns an lruCache holding up to size entries. If size is not
+// positive, nothing is cached.
+func newLRUCache[K comparable, V any](size int) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		size:    size,
+		entries: map[K]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// get returns the value for k and marks it as recently used.
+func (c *lruCache[K, V]) get(k K) (V, bool) {
+	if e, ok := c.entries[k]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*lruEntry[K, V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// put sets the value for k, which must not be cached yet, evicting the least
+// recently used entry if the cache is full.
+func (c *lruCache[K, V]) put(k K, v V) {
+	if c.size <= 0 {
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+		c.order.Remove(oldest)
+	}
+	c.entries[k] = c.order.PushFront(&lruEntry[K, V]{key: k, value: v})
+}
+
+func (c *lruCache[K, V]) len() int {
+	return c.order.Len()
+}
+
+func (c *lruCache[K, V]) clear() {
+	clear(c.entries)
+	c.order.Init()
+}
@@ -301,24 +301,8 @@ func SetModuleRegoVersion(mod *Module, v RegoVersion) {
 // Compare returns an integer indicating whether mod is less than, equal to,
 // or greater than other.
 func (mod *Module) Compare(other *Module) int {
-	if mod == nil {
-		if other == nil {
-			return 0
-		}
-		return -1
-	} else if other == nil {
-		return 1
-	}
-	if cmp := mod.Package.Compare(other.Package); cmp != 0 {
-		return cmp
-	}
-	if cmp := importsCompare(mod.Imports, other.Imports); cmp != 0 {
-		return cmp
-	}
-	if cmp := annotationsCompare(mod.Annotations, other.Annotations); cmp != 0 {
-		return cmp
-	}
-	return rulesCompare(mod.Rules, other.Rules)
+	var c comparer
+	return c.module(mod, other)
 }
 
 // Copy returns a deep copy of mod.
@@ -488,7 +472,10 @@ func (c *Comment) Equal(other *Comment) bool {
 }
 
 // Compare returns an integer indicating whether pkg is less than, equal to,
-// or greater than other.
+// or greater than other. Paths are compared component by component, and a
+// path sorts before the paths it is a prefix of, so packages are ordered like
+// a pre-order traversal of the package tree, e.g. data.a.b < data.a.b.x <
+// data.a.c. See ComparePackagePath.
 func (pkg *Package) Compare(other *Package) int {
 	return termSliceCompare(pkg.Path, other.Path)
 }
@@ -571,19 +558,8 @@ func IsValidImportPath(v Value) (err error) {
 // Compare returns an integer indicating whether imp is less than, equal to,
 // or greater than other.
 func (imp *Import) Compare(other *Import) int {
-	if imp == nil {
-		if other == nil {
-			return 0
-		}
-		return -1
-	} else if other == nil {
-		return 1
-	}
-	if cmp := Compare(imp.Path, other.Path); cmp != 0 {
-		return cmp
-	}
-
-	return VarCompare(imp.Alias, other.Alias)
+	var c comparer
+	return c.imp(imp, other)
 }
 
 // Copy returns a deep copy of imp.
@@ -659,32 +635,8 @@ func (imp *Import) MarshalJSON() ([]byte, error) {
 // Compare returns an integer indicating whether rule is less than, equal to,
 // or greater than other.
 func (rule *Rule) Compare(other *Rule) int {
-	if rule == nil {
-		if other == nil {
-			return 0
-		}
-		return -1
-	} else if other == nil {
-		return 1
-	}
-	if cmp := rule.Head.Compare(other.Head); cmp != 0 {
-		return cmp
-	}
-	if rule.Default != other.Default {
-		if !rule.Default {
-			return -1
-		}
-		return 1
-	}
-	if cmp := rule.Body.Compare(other.Body); cmp != 0 {
-		return cmp
-	}
-
-	if cmp := annotationsCompare(rule.Annotations, other.Annotations); cmp != 0 {
-		return cmp
-	}
-
-	return rule.Else.Compare(other.Else)
+	var c comparer
+	return c.rule(rule, other)
 }
 
 // Copy returns a deep copy of rule.
@@ -945,32 +897,8 @@ func (head *Head) SetRef(r Ref) {
 // Compare returns an integer indicating whether head is less than, equal to,
 // or greater than other.
 func (head *Head) Compare(other *Head) int {
-	if head == nil {
-		if other == nil {
-			return 0
-		}
-		return -1
-	} else if other == nil {
-		return 1
-	}
-	if head.Assign && !other.Assign {
-		return -1
-	} else if !head.Assign && other.Assign {
-		return 1
-	}
-	if cmp := Compare(head.Args, other.Args); cmp != 0 {
-		return cmp
-	}
-	if cmp := Compare(head.Reference, other.Reference); cmp != 0 {
-		return cmp
-	}
-	if cmp := VarCompare(head.Name, other.Name); cmp != 0 {
-		return cmp
-	}
-	if cmp := Compare(head.Key, other.Key); cmp != 0 {
-		return cmp
-	}
-	return Compare(head.Value, other.Value)
+	var c comparer
+	return c.head(head, other)
 }
 
 // Copy returns a deep copy of head.
@@ -1163,19 +1091,8 @@ func (body Body) Set(expr *Expr, pos int) {
 //
 // If body is a subset of other, it is considered less than (and vice versa).
 func (body Body) Compare(other Body) int {
-	minLen := min(len(other), len(body))
-	for i := range minLen {
-		if cmp := body[i].Compare(other[i]); cmp != 0 {
-			return cmp
-		}
-	}
-	if len(body) < len(other) {
-		return -1
-	}
-	if len(other) < len(body) {
-		return 1
-	}
-	return 0
+	var c comparer
+	return c.body(body, other)
 }
 
 // Copy returns a deep copy of body.
@@ -1297,58 +1214,8 @@ func (expr *Expr) Equal(other *Expr) bool {
 // Otherwise, the expression terms are compared normally. If both expressions
 // have the same terms, the modifiers are compared.
 func (expr *Expr) Compare(other *Expr) int {
-
-	if expr == nil {
-		if other == nil {
-			return 0
-		}
-		return -1
-	} else if other == nil {
-		return 1
-	}
-
-	o1 := expr.sortOrder()
-	o2 := other.sortOrder()
-	if o1 < o2 {
-		return -1
-	} else if o2 < o1 {
-		return 1
-	}
-
-	switch {
-	case expr.Index < other.Index:
-		return -1
-	case expr.Index > other.Index:
-		return 1
-	}
-
-	switch {
-	case expr.Negated && !other.Negated:
-		return 1
-	case !expr.Negated && other.Negated:
-		return -1
-	}
-
-	switch t := expr.Terms.(type) {
-	case *Term:
-		if cmp := Compare(t.Value, other.Terms.(*Term).Value); cmp != 0 {
-			return cmp
-		}
-	case []*Term:
-		if cmp := termSliceCompare(t, other.Terms.([]*Term)); cmp != 0 {
-			return cmp
-		}
-	case *SomeDecl:
-		if cmp := Compare(t, other.Terms.(*SomeDecl)); cmp != 0 {
-			return cmp
-		}
-	case *Every:
-		if cmp := Compare(t, other.Terms.(*Every)); cmp != 0 {
-			return cmp
-		}
-	}
-
-	return withSliceCompare(expr.With, other.With)
+	var c comparer
+	return c.expr(expr, other)
 }
 
 func (expr *Expr) sortOrder() int {
@@ -1748,16 +1615,8 @@ func (q *Every) Copy() *Every {
 }
 
 func (q *Every) Compare(other *Every) int {
-	for _, terms := range [][2]*Term{
-		{q.Key, other.Key},
-		{q.Value, other.Value},
-		{q.Domain, other.Domain},
-	} {
-		if d := Compare(terms[0], terms[1]); d != 0 {
-			return d
-		}
-	}
-	return q.Body.Compare(other.Body)
+	var c comparer
+	return c.every(q, other)
 }
 
 // KeyValueVars returns the key and val arguments of an `every`
@@ -1800,18 +1659,8 @@ func (w *With) Equal(other *With) bool {
 // Compare returns an integer indicating whether w is less than, equal to, or
 // greater than other.
 func (w *With) Compare(other *With) int {
-	if w == nil {
-		if other == nil {
-			return 0
-		}
-		return -1
-	} else if other == nil {
-		return 1
-	}
-	if cmp := Compare(w.Target, other.Target); cmp != 0 {
-		return cmp
-	}
-	return Compare(w.Value, other.Value)
+	var c comparer
+	return c.with(w, other)
 }
 
 // Copy returns a deep copy of w.
@@ -0,0 +1,52 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+// GenRandomValue returns a random ground value nested at most depth levels
+// deep, using rng as the source of randomness. Scalars are drawn from small
+// domains, so that values generated with the same rng are often equal or only
+// differ slightly. It is intended for tests and benchmarks of functions like
+// Compare and Hash.
+func GenRandomValue(rng *rand.Rand, depth int) Value {
+	n := 5
+	if depth > 0 {
+		n = 8
+	}
+	switch rng.Intn(n) {
+	case 0:
+		return Null{}
+	case 1:
+		return Boolean(rng.Intn(2) == 0)
+	case 2:
+		return Number(strconv.Itoa(rng.Intn(20) - 10))
+	case 3:
+		return Number(strconv.FormatFloat(float64(rng.Intn(200)-100)/8, 'f', -1, 64))
+	case 4:
+		return String(string(rune('a' + rng.Intn(5))))
+	case 5:
+		elems := make([]*Term, rng.Intn(4))
+		for i := range elems {
+			elems[i] = NewTerm(GenRandomValue(rng, depth-1))
+		}
+		return NewArray(elems...)
+	case 6:
+		obj := NewObject()
+		for range rng.Intn(4) {
+			obj.Insert(NewTerm(GenRandomValue(rng, 0)), NewTerm(GenRandomValue(rng, depth-1)))
+		}
+		return obj
+	default:
+		set := NewSet()
+		for range rng.Intn(4) {
+			set.Add(NewTerm(GenRandomValue(rng, depth-1)))
+		}
+		return set
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "slices"
+
+// RefTrieNode is a node in a prefix tree of refs built by BuildRefTrie.
+type RefTrieNode struct {
+	// Key is the ref term leading to this node, or nil for the root.
+	Key *Term
+
+	// Children are the nodes for the terms following Key, sorted by Compare
+	// of their keys. Keys are unique according to Compare, so 1 and 1.0 share
+	// a node.
+	Children []*RefTrieNode
+
+	// Terminal is true if a ref ends at this node, even if other refs
+	// continue past it.
+	Terminal bool
+}
+
+// BuildRefTrie returns the root of a prefix tree of refs, where each path from
+// the root to a terminal node spells one of refs. The root itself is terminal
+// only if refs contains an empty ref. Duplicate refs are merged, and the key
+// of a node is the term of the first ref in sorted order that leads to it.
+func BuildRefTrie(refs []Ref) *RefTrieNode {
+	sorted := slices.Clone(refs)
+	slices.SortStableFunc(sorted, func(a, b Ref) int { return termSliceCompare(a, b) })
+
+	// Refs that share a prefix are adjacent once sorted, and their next terms
+	// are in ascending order, so each ref either continues along the last
+	// child of a node or appends a new one.
+	root := &RefTrieNode{}
+	for _, ref := range sorted {
+		node := root
+		for _, term := range ref {
+			if n := len(node.Children); n > 0 && Compare(node.Children[n-1].Key, term) == 0 {
+				node = node.Children[n-1]
+				continue
+			}
+			child := &RefTrieNode{Key: term}
+			node.Children = append(node.Children, child)
+			node = child
+		}
+		node.Terminal = true
+	}
+	return root
+}
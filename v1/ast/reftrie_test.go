@@ -0,0 +1,121 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// refTrieString renders n with one line per node, indented by depth, and
+// terminal nodes marked with "*".
+func refTrieString(n *RefTrieNode) string {
+	var sb strings.Builder
+	var walk func(n *RefTrieNode, depth int)
+	walk = func(n *RefTrieNode, depth int) {
+		for _, c := range n.Children {
+			sb.WriteString(strings.Repeat("  ", depth))
+			sb.WriteString(c.Key.String())
+			if c.Terminal {
+				sb.WriteString(" *")
+			}
+			sb.WriteString("\n")
+			walk(c, depth+1)
+		}
+	}
+	walk(n, 0)
+	return sb.String()
+}
+
+func TestBuildRefTrie(t *testing.T) {
+	refs := []Ref{
+		MustParseRef(`data.b.c`),
+		MustParseRef(`data.a[1].x`),
+		MustParseRef(`data.a`),
+		MustParseRef(`data.a[x]`),
+		MustParseRef(`input.z`),
+		MustParseRef(`data.a[1.0]`),
+		MustParseRef(`data.a["b"]`),
+		MustParseRef(`data.b.c`),
+		MustParseRef(`data.a[1].w`),
+	}
+
+	exp := `data
+  "a" *
+    1.0 *
+      "w" *
+      "x" *
+    "b" *
+    x *
+  "b"
+    "c" *
+input
+  "z" *
+`
+	root := BuildRefTrie(refs)
+	if act := refTrieString(root); act != exp {
+		t.Fatalf("Expected trie:\n%v\nGot:\n%v", exp, act)
+	}
+	if root.Key != nil || root.Terminal {
+		t.Fatal("Expected root without key that is not terminal")
+	}
+	if !BuildRefTrie([]Ref{{}}).Terminal {
+		t.Fatal("Expected root to be terminal for empty ref")
+	}
+	if len(BuildRefTrie(nil).Children) != 0 {
+		t.Fatal("Expected empty trie for no refs")
+	}
+	if !refs[0].Equal(MustParseRef(`data.b.c`)) {
+		t.Fatal("Expected refs not to be reordered")
+	}
+}
+
+func TestBuildRefTrieRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keys := []*Term{
+		VarTerm("data"), VarTerm("x"), StringTerm("a"), StringTerm("b"),
+		IntNumberTerm(1), FloatNumberTerm(1.0), BooleanTerm(true), NullTerm(),
+	}
+	for range 100 {
+		refs := make([]Ref, rng.Intn(20))
+		for i := range refs {
+			refs[i] = make(Ref, rng.Intn(5))
+			for j := range refs[i] {
+				refs[i][j] = keys[rng.Intn(len(keys))]
+			}
+		}
+
+		var terminal []Ref
+		var walk func(n *RefTrieNode, prefix Ref)
+		walk = func(n *RefTrieNode, prefix Ref) {
+			if n.Terminal {
+				terminal = append(terminal, prefix)
+			}
+			if !slices.IsSortedFunc(n.Children, func(a, b *RefTrieNode) int { return Compare(a.Key, b.Key) }) {
+				t.Fatalf("Expected sorted children for %v", prefix)
+			}
+			for i := 1; i < len(n.Children); i++ {
+				if Compare(n.Children[i-1].Key, n.Children[i].Key) == 0 {
+					t.Fatalf("Expected unique children for %v", prefix)
+				}
+			}
+			for _, c := range n.Children {
+				walk(c, append(slices.Clone(prefix), c.Key))
+			}
+		}
+		walk(BuildRefTrie(refs), Ref{})
+
+		// Every terminal path is one of refs, and every ref is a terminal
+		// path, in sorted order.
+		exp := slices.Clone(refs)
+		slices.SortFunc(exp, func(a, b Ref) int { return termSliceCompare(a, b) })
+		exp = slices.CompactFunc(exp, func(a, b Ref) bool { return termSliceCompare(a, b) == 0 })
+		if !slices.EqualFunc(terminal, exp, func(a, b Ref) bool { return termSliceCompare(a, b) == 0 }) {
+			t.Fatalf("Expected terminal paths %v but got %v", exp, terminal)
+		}
+	}
+}
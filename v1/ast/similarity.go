@@ -0,0 +1,82 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+// Similarity returns a heuristic measure of how similar a and b are, from 0
+// for values of different types to 1 for values that are equal according to
+// ValueEqual. It is symmetric, and is computed recursively as follows:
+//
+//   - Values that are equal score 1.
+//   - Values of different types score 0, as do unequal scalars such as
+//     numbers and strings, and unequal values of types not listed below.
+//   - Objects score the sum of the similarities of the values of the keys
+//     they share, divided by the number of keys in either object.
+//   - Arrays, refs and calls score the sum of the similarities of the
+//     elements at the same index, divided by the length of the longer one.
+//     Values that share a longer common prefix thus score higher.
+//   - Sets score the number of elements in both sets, divided by the number
+//     of elements in either set.
+//
+// For example, {"a": 1, "b": [1, 2]} and {"a": 1, "b": [1, 3], "c": 1} score
+// (1 + 1/2) / 3 = 0.5.
+func Similarity(a, b Value) float64 {
+	if a == nil || b == nil {
+		if a == nil && b == nil {
+			return 1
+		}
+		return 0
+	}
+	if ValueEqual(a, b) {
+		return 1
+	}
+	if sortOrder(a) != sortOrder(b) {
+		return 0
+	}
+
+	switch a := a.(type) {
+	case Object:
+		return objectSimilarity(a, b.(Object))
+	case *Array:
+		return termSliceSimilarity(a.elems, b.(*Array).elems)
+	case Ref:
+		return termSliceSimilarity(a, b.(Ref))
+	case Call:
+		return termSliceSimilarity(a, b.(Call))
+	case Set:
+		return setSimilarity(a, b.(Set))
+	}
+	return 0
+}
+
+func objectSimilarity(a, b Object) float64 {
+	var sum float64
+	union := b.Len()
+	a.Foreach(func(k, v *Term) {
+		if w := b.Get(k); w != nil {
+			sum += Similarity(v.Value, w.Value)
+		} else {
+			union++
+		}
+	})
+	return sum / float64(union)
+}
+
+func termSliceSimilarity(a, b []*Term) float64 {
+	var sum float64
+	for i := range min(len(a), len(b)) {
+		sum += Similarity(a[i].Value, b[i].Value)
+	}
+	return sum / float64(max(len(a), len(b)))
+}
+
+func setSimilarity(a, b Set) float64 {
+	shared := 0
+	a.Foreach(func(x *Term) {
+		if b.Contains(x) {
+			shared++
+		}
+	})
+	return float64(shared) / float64(a.Len()+b.Len()-shared)
+}
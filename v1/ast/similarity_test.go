@@ -0,0 +1,100 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  float64
+	}{
+		{`1`, `1.0`, 1},
+		{`{"a": [1, {2}]}`, `{"a": [1.0, {2.0}]}`, 1},
+		{`1`, `2`, 0},
+		{`1`, `"1"`, 0},
+		{`[1]`, `{1}`, 0},
+		{`{"a": 1}`, `{"b": 1}`, 0},
+		{`{"a": 1, "b": [1, 2]}`, `{"a": 1, "b": [1, 3], "c": 1}`, 0.5},
+		{`{"a": 1, "b": 2}`, `{"a": 1, "b": 3}`, 0.5},
+		{`[1, 2, 3, 4]`, `[1, 2]`, 0.5},
+		{`[1, 2, 3, 4]`, `[1, 5, 3, 4]`, 0.75},
+		{`[]`, `[1]`, 0},
+		{`{1, 2, 3}`, `{2, 3, 4}`, 0.5},
+		{`{1, 2}`, `set()`, 0},
+		{`data.a.b`, `data.a.c`, 2.0 / 3},
+		{`[[1, 2]]`, `[[1, 3], 4]`, 0.25},
+	}
+
+	for _, tc := range tests {
+		a, b := MustParseTerm(tc.a).Value, MustParseTerm(tc.b).Value
+		if act := Similarity(a, b); math.Abs(act-tc.exp) > 1e-9 {
+			t.Errorf("Expected %v for %v and %v but got %v", tc.exp, a, b, act)
+		}
+		if act := Similarity(b, a); math.Abs(act-tc.exp) > 1e-9 {
+			t.Errorf("Expected %v for %v and %v but got %v", tc.exp, b, a, act)
+		}
+	}
+
+	if Similarity(nil, nil) != 1 || Similarity(nil, Null{}) != 0 {
+		t.Fatal("Unexpected similarity for nil")
+	}
+}
+
+func TestSimilarityMonotonic(t *testing.T) {
+	// Each value differs from the first in one more place than the previous.
+	values := []string{
+		`{"name": "a", "tags": ["x", "y", "z"], "roles": {"r1", "r2"}, "meta": {"n": 1, "m": 2}}`,
+		`{"name": "a", "tags": ["x", "y", "z"], "roles": {"r1", "r2"}, "meta": {"n": 1, "m": 3}}`,
+		`{"name": "a", "tags": ["x", "y", "q"], "roles": {"r1", "r2"}, "meta": {"n": 1, "m": 3}}`,
+		`{"name": "a", "tags": ["x", "y", "q"], "roles": {"r1", "r3"}, "meta": {"n": 1, "m": 3}}`,
+		`{"name": "a", "tags": ["x", "q", "q"], "roles": {"r1", "r3"}, "meta": {"n": 1, "m": 3}}`,
+		`{"name": "b", "tags": ["x", "q", "q"], "roles": {"r1", "r3"}, "meta": {"n": 1, "m": 3}}`,
+		`{"name": "b", "tags": ["x", "q", "q"], "roles": {"r3"}, "meta": {"n": 1, "m": 3}}`,
+		`{"name": "b", "tags": "x", "roles": {"r3"}, "meta": {"n": 1, "m": 3}}`,
+		`{"other": 1, "meta": {"n": 1}}`,
+		`{"other": 1}`,
+	}
+
+	base := MustParseTerm(values[0]).Value
+	prev := 1.0
+	for _, s := range values {
+		act := Similarity(base, MustParseTerm(s).Value)
+		if s != values[0] && act >= prev {
+			t.Fatalf("Expected similarity to decrease below %v for %v but got %v", prev, s, act)
+		}
+		prev = act
+	}
+	if prev != 0 {
+		t.Fatalf("Expected 0 for an object without common keys but got %v", prev)
+	}
+	if act := Similarity(base, String("a")); act != 0 {
+		t.Fatalf("Expected 0 for a different type but got %v", act)
+	}
+}
+
+func TestSimilarityRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for range 1000 {
+		a, b := GenRandomValue(rng, 3), GenRandomValue(rng, 3)
+		if rng.Intn(4) == 0 {
+			b = respell(rng, a)
+		}
+		act := Similarity(a, b)
+		if act < 0 || act > 1 {
+			t.Fatalf("Expected similarity in [0, 1] for %v and %v but got %v", a, b, act)
+		}
+		if act != Similarity(b, a) {
+			t.Fatalf("Expected symmetric similarity for %v and %v", a, b)
+		}
+		if (act == 1) != ValueEqual(a, b) {
+			t.Fatalf("Expected similarity 1 only for equal values, got %v for %v and %v", act, a, b)
+		}
+	}
+}
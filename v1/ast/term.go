@@ -765,6 +765,15 @@ func (num Number) String() string {
 	return string(num)
 }
 
+// numberEqualSlow returns true if x and y are equal numbers. It is used by the
+// inlined equality functions below when y is not a plain int64, since it may
+// still be the same integer spelled differently, e.g. 1e2. Malformed numbers
+// are not equal to anything.
+func numberEqualSlow(x, y Number) bool {
+	c, ok := tryCompareNumbers(x, y)
+	return ok && c == 0
+}
+
 func intNumber(i int) Number {
 	return Number(strconv.Itoa(i))
 }
@@ -1243,6 +1252,51 @@ func (ref Ref) toArray() *Array {
 	return NewArray(terms...)
 }
 
+// RefWarning describes a possibly ambiguous element of a ref, as reported by
+// RefIndexTypeCheck.
+type RefWarning struct {
+	// Index is the position of the element in the ref.
+	Index int
+	// Term is the element.
+	Term *Term
+	// Message describes the ambiguity.
+	Message string
+}
+
+// RefIndexTypeCheck reports the elements of r, other than its head, that are
+// strings spelling an array index, e.g. "0" in data.x["0"]. Strings and
+// numbers are different types, so data.x["0"] and data.x[0] are different
+// refs: the former never matches an element of an array. Only non-negative
+// integers in canonical form, e.g. "0" and "12" but not "01" or "-1", are
+// reported.
+func RefIndexTypeCheck(r Ref) []RefWarning {
+	var warnings []RefWarning
+	for i := 1; i < len(r); i++ {
+		s, ok := r[i].Value.(String)
+		if !ok || !isArrayIndexString(string(s)) {
+			continue
+		}
+		warnings = append(warnings, RefWarning{
+			Index:   i,
+			Term:    r[i],
+			Message: fmt.Sprintf("string %v looks like an array index but does not match array elements, use %s instead", s, string(s)),
+		})
+	}
+	return warnings
+}
+
+func isArrayIndexString(s string) bool {
+	if s == "" || (s[0] == '0' && len(s) > 1) {
+		return false
+	}
+	for i := range len(s) {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // QueryIterator defines the interface for querying AST documents with references.
 type QueryIterator func(map[Var]Value, Value) error
 
@@ -1367,18 +1421,27 @@ func (arr *Array) Get(pos *Term) *Term {
 	return nil
 }
 
-// Sorted returns a new Array that contains the sorted elements of arr.
+// Sorted returns a new Array that contains the elements of arr sorted by
+// Compare. The sort is stable, so elements that compare equal, e.g. 1 and 1.0,
+// keep their relative order. arr is not modified.
 func (arr *Array) Sorted() *Array {
-	cpy := make([]*Term, len(arr.elems))
-	for i := range cpy {
-		cpy[i] = arr.elems[i]
+	order := make([]int, len(arr.elems))
+	for i := range order {
+		order[i] = i
 	}
+	slices.SortStableFunc(order, func(i, j int) int {
+		return TermValueCompare(arr.elems[i], arr.elems[j])
+	})
 
-	slices.SortFunc(cpy, TermValueCompare)
-
-	a := NewArray(cpy...)
-	a.hashs = arr.hashs
-	return a
+	// Reorder the element hashes along with the elements, so that they don't
+	// need to be recomputed.
+	elems := make([]*Term, len(order))
+	hashs := make([]int, len(order))
+	for i, j := range order {
+		elems[i] = arr.elems[j]
+		hashs[i] = arr.hashs[j]
+	}
+	return &Array{elems: elems, hashs: hashs, hash: arr.hash, ground: arr.ground}
 }
 
 // Hash returns the hash code for the Value.
@@ -1510,8 +1573,11 @@ type Set interface {
 	Len() int
 	Copy() Set
 	Diff(Set) Set
+	SortedDifference(Set) []*Term
 	Intersect(Set) Set
 	Union(Set) Set
+	Subset(Set) bool
+	ProperSubset(Set) bool
 	Add(*Term)
 	Iter(func(*Term) error) error
 	Until(func(*Term) bool) bool
@@ -1521,6 +1587,7 @@ type Set interface {
 	Reduce(*Term, func(*Term, *Term) (*Term, error)) (*Term, error)
 	Sorted() *Array
 	Slice() []*Term
+	GroupedSortedSlice() [][]*Term
 }
 
 // NewSet returns a new Set containing t.
@@ -1564,6 +1631,10 @@ type set struct {
 	// methods of `set` use a pointer receiver, and the `sync.Once` value
 	// is never copied.
 	sortGuard sync.Once
+	// Set once keys have been sorted, and thus may have been handed out by
+	// Slice. The next insert then appends to a copy so that re-sorting does
+	// not reorder slices returned earlier.
+	sorted bool
 }
 
 // Copy returns a deep copy of s.
@@ -1583,7 +1654,9 @@ func (s *set) IsGround() bool {
 	return s.ground
 }
 
-// Hash returns a hash code for s.
+// Hash returns a hash code for s. The hash does not depend on the order in
+// which elements were added, so sets that compare equal have the same hash.
+// See also the Hash function, which returns a 64-bit hash for any Value.
 func (s *set) Hash() int {
 	return s.hash
 }
@@ -1613,22 +1686,32 @@ func (s *set) String() string {
 func (s *set) sortedKeys() []*Term {
 	s.sortGuard.Do(func() {
 		slices.SortFunc(s.keys, TermValueCompare)
+		s.sorted = true
 	})
 	return s.keys
 }
 
+// Equal returns true if other is a Set containing the same elements as s. Sets
+// of different size or hash are rejected without looking at their elements,
+// and otherwise the comparison stops at the first differing element.
+func (s *set) Equal(other Value) bool {
+	o, ok := other.(*set)
+	if !ok {
+		return false
+	}
+	if s == o {
+		return true
+	}
+	if s.Len() != o.Len() || s.hash != o.hash {
+		return false
+	}
+	return termSliceEqual(s.sortedKeys(), o.sortedKeys())
+}
+
 // Compare compares s to other, return <0, 0, or >0 if it is less than, equal to,
 // or greater than other.
 func (s *set) Compare(other Value) int {
-	o1 := sortOrder(s)
-	o2 := sortOrder(other)
-	if o1 < o2 {
-		return -1
-	} else if o1 > o2 {
-		return 1
-	}
-	t := other.(*set)
-	return termSliceCompare(s.sortedKeys(), t.sortedKeys())
+	return compare(s, other)
 }
 
 // Find returns the set or dereferences the element itself.
@@ -1648,14 +1731,19 @@ func (s *set) Diff(other Set) Set {
 		return NewSet()
 	}
 
+	return NewSet(s.SortedDifference(other)...)
+}
+
+// SortedDifference returns the elements of s that are not in other, ordered by
+// Compare.
+func (s *set) SortedDifference(other Set) []*Term {
 	terms := make([]*Term, 0, len(s.keys))
 	for _, term := range s.sortedKeys() {
 		if !other.Contains(term) {
 			terms = append(terms, term)
 		}
 	}
-
-	return NewSet(terms...)
+	return terms
 }
 
 // Intersect returns the set containing elements in both s and other.
@@ -1680,6 +1768,26 @@ func (s *set) Intersect(other Set) Set {
 	return NewSet(terms...)
 }
 
+// Subset returns true if every element of s is also an element of other. It
+// stops at the first element that is missing from other.
+func (s *set) Subset(other Set) bool {
+	if s.Len() > other.Len() {
+		return false
+	}
+	for _, term := range s.keys {
+		if !other.Contains(term) {
+			return false
+		}
+	}
+	return true
+}
+
+// ProperSubset returns true if s is a subset of other and other contains at
+// least one element that is not in s.
+func (s *set) ProperSubset(other Set) bool {
+	return s.Len() < other.Len() && s.Subset(other)
+}
+
 // Union returns the set containing all elements of s and other.
 func (s *set) Union(other Set) Set {
 	r := NewSet()
@@ -1769,11 +1877,32 @@ func (s *set) Sorted() *Array {
 	return NewArray(cpy...)
 }
 
-// Slice returns a slice of terms contained in the set.
+// Slice returns a slice of terms contained in the set, in the order defined by
+// Compare. The order is computed once and cached until the set is modified.
+// The returned slice must not be modified, and is not affected by later
+// modifications of the set.
 func (s *set) Slice() []*Term {
 	return s.sortedKeys()
 }
 
+// GroupedSortedSlice returns the terms contained in the set grouped by type,
+// with one slice per type that occurs in the set. The groups are ordered by
+// TypeOrder, and the terms in each group by Compare, so concatenating the
+// groups gives the same result as Slice. Like Slice, the returned slices must
+// not be modified.
+func (s *set) GroupedSortedSlice() [][]*Term {
+	keys := s.sortedKeys()
+	var groups [][]*Term
+	start := 0
+	for i := 1; i <= len(keys); i++ {
+		if i == len(keys) || sortOrder(keys[i].Value) != sortOrder(keys[start].Value) {
+			groups = append(groups, keys[start:i:i])
+			start = i
+		}
+	}
+	return groups
+}
+
 // NOTE(philipc): We assume a many-readers, single-writer model here.
 // This method should NOT be used concurrently, or else we risk data races.
 func (s *set) insert(x *Term, resetSortGuard bool) {
@@ -1795,6 +1924,7 @@ func (s *set) insert(x *Term, resetSortGuard bool) {
 					if yi, err := json.Number(y).Int64(); err == nil {
 						return xi == yi
 					}
+					return numberEqualSlow(x, y)
 				}
 
 				return false
@@ -1867,6 +1997,10 @@ func (s *set) insert(x *Term, resetSortGuard bool) {
 
 	s.elems[insertHash] = x
 	// O(1) insertion, but we'll have to re-sort the keys later.
+	if s.sorted {
+		s.keys = slices.Clip(s.keys)
+		s.sorted = false
+	}
 	s.keys = append(s.keys, x)
 
 	if resetSortGuard {
@@ -1878,7 +2012,10 @@ func (s *set) insert(x *Term, resetSortGuard bool) {
 		s.sortGuard = sync.Once{}
 	}
 
-	s.hash += hash
+	// Mix the element hashes before summing them, so that the hash stays
+	// independent of insertion order, but sets like {1, 4} and {2, 3} whose
+	// element hashes have the same sum do not collide.
+	s.hash += int(hashFinalize(uint64(hash)))
 	s.ground = s.ground && x.IsGround()
 }
 
@@ -1900,6 +2037,7 @@ func (s *set) get(x *Term) *Term {
 					if yi, err := json.Number(y).Int64(); err == nil {
 						return xi == yi
 					}
+					return numberEqualSlow(x, y)
 				}
 
 				return false
@@ -1983,6 +2121,7 @@ type Object interface {
 	Iter(func(*Term, *Term) error) error
 	Until(func(*Term, *Term) bool) bool
 	Foreach(func(*Term, *Term))
+	SortedIter(func(k, v *Term) bool)
 	Map(func(*Term, *Term) (*Term, *Term, error)) (Object, error)
 	Diff(other Object) Object
 	Intersect(other Object) [][3]*Term
@@ -2003,6 +2142,21 @@ func NewObject(t ...[2]*Term) Object {
 	return obj
 }
 
+// NewSortedObject returns a new Object containing the key/value pairs in t,
+// with its keys already sorted in Compare order. Keys that are equal, like 1
+// and 1.0, are deduplicated: the value of the last pair wins, while the key
+// keeps the spelling of the first pair. Like every Object, the result is
+// iterated in sorted key order; sorting it up front means that reading it,
+// e.g. from multiple goroutines, does not need to sort it again.
+func NewSortedObject(t ...[2]*Term) Object {
+	obj := newobject(len(t))
+	for i := range t {
+		obj.insert(t[i][0], t[i][1], false)
+	}
+	obj.sortedKeys()
+	return obj
+}
+
 // ObjectTerm creates a new Term with an Object value.
 func ObjectTerm(o ...[2]*Term) *Term {
 	return &Term{Value: NewObject(o...)}
@@ -2030,14 +2184,7 @@ func (l *lazyObj) force() Object {
 }
 
 func (l *lazyObj) Compare(other Value) int {
-	o1 := sortOrder(l)
-	o2 := sortOrder(other)
-	if o1 < o2 {
-		return -1
-	} else if o2 < o1 {
-		return 1
-	}
-	return l.force().Compare(other)
+	return compare(l, other)
 }
 
 func (l *lazyObj) Copy() Object {
@@ -2066,6 +2213,19 @@ func (l *lazyObj) Foreach(f func(*Term, *Term)) {
 	l.force().Foreach(f)
 }
 
+// SortedIter only converts the values that f is called on.
+func (l *lazyObj) SortedIter(f func(k, v *Term) bool) {
+	if l.strict != nil {
+		l.strict.SortedIter(f)
+		return
+	}
+	for _, k := range l.Keys() {
+		if !f(k, l.Get(k)) {
+			return
+		}
+	}
+}
+
 func (l *lazyObj) Filter(filter Object) (Object, error) {
 	return l.force().Filter(filter)
 }
@@ -2140,11 +2300,18 @@ func (l *lazyObj) Keys() []*Term {
 	if l.strict != nil {
 		return l.strict.Keys()
 	}
-	ret := make([]*Term, 0, len(l.native))
+	// String keys sort the same way as their String values, so sort the
+	// native keys first to avoid the overhead of comparing terms.
+	keys := make([]string, 0, len(l.native))
 	for k := range l.native {
-		ret = append(ret, StringTerm(k))
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	ret := make([]*Term, len(keys))
+	for i, k := range keys {
+		ret[i] = StringTerm(k)
 	}
-	slices.SortFunc(ret, TermValueCompare)
 
 	return ret
 }
@@ -2246,47 +2413,41 @@ func (obj *object) sortedKeys() objectElemSlice {
 // Compare compares obj to other, return <0, 0, or >0 if it is less than, equal to,
 // or greater than other.
 func (obj *object) Compare(other Value) int {
-	if x, ok := other.(*lazyObj); ok {
-		other = x.force()
-	}
-	o1 := sortOrder(obj)
-	o2 := sortOrder(other)
-	if o1 < o2 {
-		return -1
-	} else if o2 < o1 {
-		return 1
-	}
-	a := obj
-	b := other.(*object)
-	// Ensure that keys are in canonical sorted order before use!
-	akeys := a.sortedKeys()
-	bkeys := b.sortedKeys()
-	minLen := len(akeys)
-	if len(b.keys) < len(akeys) {
-		minLen = len(bkeys)
-	}
-	for i := range minLen {
-		keysCmp := Compare(akeys[i].key, bkeys[i].key)
-		if keysCmp < 0 {
-			return -1
+	return compare(obj, other)
+}
+
+// Equal returns true if other is an Object with the same key/value pairs as
+// obj. Unlike Compare, it stops at the first key/value pair that differs.
+func (obj *object) Equal(other Value) bool {
+	switch other := other.(type) {
+	case *object:
+		if obj == other {
+			return true
 		}
-		if keysCmp > 0 {
-			return 1
+		if obj.Len() != other.Len() {
+			return false
 		}
-		valA := akeys[i].value
-		valB := bkeys[i].value
-		valCmp := Compare(valA, valB)
-		if valCmp != 0 {
-			return valCmp
+		akeys := obj.sortedKeys()
+		bkeys := other.sortedKeys()
+		for i := range akeys {
+			if !akeys[i].key.Equal(bkeys[i].key) || !akeys[i].value.Equal(bkeys[i].value) {
+				return false
+			}
 		}
+		return true
+	case *lazyObj:
+		if obj.Len() != other.Len() {
+			return false
+		}
+		for _, elem := range obj.keys {
+			v := other.Get(elem.key)
+			if v == nil || !ValueEqual(elem.value.Value, v.Value) {
+				return false
+			}
+		}
+		return true
 	}
-	if len(akeys) < len(bkeys) {
-		return -1
-	}
-	if len(bkeys) < len(akeys) {
-		return 1
-	}
-	return 0
+	return false
 }
 
 // Find returns the value at the key or undefined.
@@ -2392,6 +2553,17 @@ func (obj *object) Foreach(f func(*Term, *Term)) {
 	}
 }
 
+// SortedIter calls f for each key-value pair in the object, in Compare order
+// of the keys, until f returns false. The keys are only sorted once, on first
+// use.
+func (obj *object) SortedIter(f func(k, v *Term) bool) {
+	for _, node := range obj.sortedKeys() {
+		if !f(node.key, node.value) {
+			return
+		}
+	}
+}
+
 // Map returns a new Object constructed by mapping each element in the object
 // using the function f. If f returns an error, the error is returned by Map.
 // If f return a nil key, the element is skipped.
@@ -2544,6 +2716,7 @@ func (obj *object) get(k *Term) *objectElem {
 					if yi, ok := y.Int64(); ok {
 						return xi == yi
 					}
+					return numberEqualSlow(x, y)
 				}
 
 				return false
@@ -2634,6 +2807,7 @@ func (obj *object) insert(k, v *Term, resetSortGuard bool) {
 					if yi, err := json.Number(y).Int64(); err == nil {
 						return xi == yi
 					}
+					return numberEqualSlow(x, y)
 				}
 
 				return false
@@ -2875,7 +3049,10 @@ func (ac *ArrayComprehension) Copy() *ArrayComprehension {
 
 // Equal returns true if ac is equal to other.
 func (ac *ArrayComprehension) Equal(other Value) bool {
-	return Compare(ac, other) == 0
+	if o, ok := other.(*ArrayComprehension); ok {
+		return ac.Term.Equal(o.Term) && ac.Body.Equal(o.Body)
+	}
+	return false
 }
 
 // Compare compares ac to other, return <0, 0, or >0 if it is less than, equal to,
@@ -2935,7 +3112,10 @@ func (oc *ObjectComprehension) Copy() *ObjectComprehension {
 
 // Equal returns true if oc is equal to other.
 func (oc *ObjectComprehension) Equal(other Value) bool {
-	return Compare(oc, other) == 0
+	if o, ok := other.(*ObjectComprehension); ok {
+		return oc.Key.Equal(o.Key) && oc.Value.Equal(o.Value) && oc.Body.Equal(o.Body)
+	}
+	return false
 }
 
 // Compare compares oc to other, return <0, 0, or >0 if it is less than, equal to,
@@ -2992,7 +3172,10 @@ func (sc *SetComprehension) Copy() *SetComprehension {
 
 // Equal returns true if sc is equal to other.
 func (sc *SetComprehension) Equal(other Value) bool {
-	return Compare(sc, other) == 0
+	if o, ok := other.(*SetComprehension); ok {
+		return sc.Term.Equal(o.Term) && sc.Body.Equal(o.Body)
+	}
+	return false
 }
 
 // Compare compares sc to other, return <0, 0, or >0 if it is less than, equal to,
@@ -3037,6 +3220,14 @@ func (c Call) Copy() Call {
 	return termSliceCopy(c)
 }
 
+// Equal returns true if other is a Call with equal operator and operands.
+func (c Call) Equal(other Value) bool {
+	if o, ok := other.(Call); ok {
+		return termSliceEqual(c, o)
+	}
+	return false
+}
+
 // Compare compares c to other, return <0, 0, or >0 if it is less than, equal to,
 // or greater than other.
 func (c Call) Compare(other Value) int {
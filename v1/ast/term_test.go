@@ -11,6 +11,7 @@ import (
 	"math/rand"
 	"reflect"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -162,6 +163,36 @@ func TestObjectInsertGetLen(t *testing.T) {
 	}
 }
 
+func TestNewSortedObject(t *testing.T) {
+	obj := NewSortedObject(
+		Item(StringTerm("b"), IntNumberTerm(1)),
+		Item(IntNumberTerm(1), StringTerm("first")),
+		Item(StringTerm("a"), IntNumberTerm(2)),
+		Item(NumberTerm("1.0"), StringTerm("second")),
+		Item(NullTerm(), IntNumberTerm(3)),
+		Item(NumberTerm("1e0"), StringTerm("last")),
+	)
+
+	if obj.Len() != 4 {
+		t.Fatalf("expected numeric keys to collide but got %v", obj)
+	}
+	if v := obj.Get(NumberTerm("1.00")); v == nil || !v.Equal(StringTerm("last")) {
+		t.Fatalf("expected last value to win but got %v", v)
+	}
+
+	var keys []*Term
+	obj.Foreach(func(k, _ *Term) {
+		keys = append(keys, k)
+	})
+	exp := []*Term{NullTerm(), IntNumberTerm(1), StringTerm("a"), StringTerm("b")}
+	if !termSliceEqual(keys, exp) {
+		t.Fatalf("expected keys %v but got %v", exp, keys)
+	}
+	if !termSliceEqual(obj.Keys(), exp) {
+		t.Fatalf("expected keys %v but got %v", exp, obj.Keys())
+	}
+}
+
 func TestObjectSetOperations(t *testing.T) {
 	a := MustParseTerm(`{"a": "b", "c": "d"}`).Value.(Object)
 	b := MustParseTerm(`{"c": "q", "d": "e"}`).Value.(Object)
@@ -194,6 +225,45 @@ func TestObjectSetOperations(t *testing.T) {
 	}
 }
 
+func TestObjectSortedIter(t *testing.T) {
+	obj := MustParseTerm(`{"b": 1, 2: 2, null: 3, [1]: 4, false: 5, 1.5: 6, {"x"}: 7, "a": 8, {"k": 1}: 9}`).Value.(Object)
+	exp := []string{`null`, `false`, `1.5`, `2`, `"a"`, `"b"`, `[1]`, `{"k": 1}`, `{"x"}`}
+
+	var act []string
+	obj.SortedIter(func(k, v *Term) bool {
+		if !obj.Get(k).Equal(v) {
+			t.Fatalf("Expected value %v for key %v but got %v", obj.Get(k), k, v)
+		}
+		act = append(act, k.String())
+		return true
+	})
+	if !slices.Equal(act, exp) {
+		t.Fatalf("Expected keys %v but got %v", exp, act)
+	}
+
+	act = nil
+	obj.SortedIter(func(k, _ *Term) bool {
+		act = append(act, k.String())
+		return len(act) < 3
+	})
+	if !slices.Equal(act, exp[:3]) {
+		t.Fatalf("Expected iteration to stop after %v but got %v", exp[:3], act)
+	}
+
+	lazy := LazyObject(map[string]any{"c": 1, "a": 2, "b": 3})
+	act = nil
+	lazy.SortedIter(func(k, _ *Term) bool {
+		act = append(act, k.String())
+		return true
+	})
+	if exp := []string{`"a"`, `"b"`, `"c"`}; !slices.Equal(act, exp) {
+		t.Fatalf("Expected keys %v but got %v", exp, act)
+	}
+	if lazy.(*lazyObj).strict != nil {
+		t.Fatal("Expected lazy object not to be forced")
+	}
+}
+
 func TestObjectFilter(t *testing.T) {
 	cases := []struct {
 		note     string
@@ -432,7 +502,7 @@ func TestHashSet(t *testing.T) {
 	// Calculate hash like we did before moving the caching to create/update:
 	exp := 0
 	set1.Foreach(func(x *Term) {
-		exp += x.Hash()
+		exp += int(hashFinalize(uint64(x.Hash())))
 	})
 
 	if act := set1.Hash(); exp != act {
@@ -813,6 +883,47 @@ func TestRefPtr(t *testing.T) {
 	}
 }
 
+func TestRefIndexTypeCheck(t *testing.T) {
+	tests := []struct {
+		ref     string
+		indices []int
+	}{
+		{`data.x[0]`, nil},
+		{`data.x["0"]`, []int{2}},
+		{`data.x["0"][1]["12"].y`, []int{2, 4}},
+		{`data.x["a"]["01"]["-1"]["1.0"][""]`, nil},
+		{`input.x[i]["3"]`, []int{3}},
+		{`x["0"]`, []int{1}},
+		{`data.x[[0]]["9"]`, []int{3}},
+	}
+
+	for _, tc := range tests {
+		r := MustParseRef(tc.ref)
+		warnings := RefIndexTypeCheck(r)
+		var indices []int
+		for _, w := range warnings {
+			indices = append(indices, w.Index)
+			if w.Term != r[w.Index] {
+				t.Errorf("Expected term %v for %v but got %v", r[w.Index], tc.ref, w.Term)
+			}
+		}
+		if !slices.Equal(indices, tc.indices) {
+			t.Errorf("Expected warnings at %v for %v but got %v", tc.indices, tc.ref, indices)
+		}
+	}
+
+	// The head is not an index.
+	if warnings := RefIndexTypeCheck(Ref{StringTerm("0"), StringTerm("1")}); len(warnings) != 1 || warnings[0].Index != 1 {
+		t.Fatalf("Expected a single warning for the second element but got %v", warnings)
+	}
+
+	warnings := RefIndexTypeCheck(MustParseRef(`data.x["0"]`))
+	exp := `string "0" looks like an array index but does not match array elements, use 0 instead`
+	if warnings[0].Message != exp {
+		t.Fatalf("Expected message %q but got %q", exp, warnings[0].Message)
+	}
+}
+
 func TestSetEqual(t *testing.T) {
 	tests := []struct {
 		a        string
@@ -938,6 +1049,112 @@ func TestSetOperations(t *testing.T) {
 	}
 }
 
+func TestSetSubset(t *testing.T) {
+	tests := []struct {
+		a, b           string
+		subset, proper bool
+	}{
+		{`set()`, `set()`, true, false},
+		{`set()`, `{1}`, true, true},
+		{`{1}`, `set()`, false, false},
+		{`{1, 2}`, `{2, 1.0}`, true, false},
+		{`{1, 2}`, `{1, 2, 3}`, true, true},
+		{`{1, 2, 3}`, `{1, 2}`, false, false},
+		{`{1, 2}`, `{3, 4}`, false, false},
+		{`{1, 4}`, `{1, 2, 3}`, false, false},
+		{`{[1], {"a": 2}}`, `{{"a": 2}, [1], 3}`, true, true},
+	}
+
+	for _, tc := range tests {
+		a := MustParseTerm(tc.a).Value.(Set)
+		b := MustParseTerm(tc.b).Value.(Set)
+		if act := a.Subset(b); act != tc.subset {
+			t.Errorf("Expected %v.Subset(%v) to be %v", tc.a, tc.b, tc.subset)
+		}
+		if act := a.ProperSubset(b); act != tc.proper {
+			t.Errorf("Expected %v.ProperSubset(%v) to be %v", tc.a, tc.b, tc.proper)
+		}
+	}
+}
+
+func TestSetSortedDifference(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  string
+	}{
+		{`set()`, `set()`, `[]`},
+		{`{3, 1, 2}`, `{3, 1, 2}`, `[]`},
+		{`{3, "a", 1, 2}`, `set()`, `[1, 2, 3, "a"]`},
+		{`{3, "a", 1, 2}`, `{2.0, "b"}`, `[1, 3, "a"]`},
+		{`{[1], {"a": 2}, null}`, `{{"a": 2}}`, `[null, [1]]`},
+	}
+
+	for _, tc := range tests {
+		a := MustParseTerm(tc.a).Value.(Set)
+		b := MustParseTerm(tc.b).Value.(Set)
+		if act := NewArray(a.SortedDifference(b)...).String(); act != tc.exp {
+			t.Errorf("Expected %v.SortedDifference(%v) to be %v but got %v", tc.a, tc.b, tc.exp, act)
+		}
+	}
+
+	s := MustParseTerm(`{1, 2, 3}`).Value.(Set)
+	if d := s.SortedDifference(s); len(d) != 0 {
+		t.Fatalf("Expected empty self-difference but got %v", d)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for range 500 {
+		a, b := NewSet(), NewSet()
+		for range rng.Intn(10) {
+			a.Add(IntNumberTerm(rng.Intn(10)))
+		}
+		for range rng.Intn(10) {
+			b.Add(IntNumberTerm(rng.Intn(10)))
+		}
+		diff := a.SortedDifference(b)
+		if !slices.IsSortedFunc(diff, termCompare) {
+			t.Fatalf("Expected %v to be sorted", diff)
+		}
+		if union := NewSet(diff...).Union(a.Intersect(b)); union.Compare(a) != 0 {
+			t.Fatalf("Expected %v.SortedDifference(%v) unioned with the intersection to be %v but got %v", a, b, a, union)
+		}
+	}
+}
+
+func TestSetGroupedSortedSlice(t *testing.T) {
+	s := MustParseTerm(`{"b", 2, [1], null, "a", 1.5, {"x": 1}, [0], false, {3}}`).Value.(Set)
+	exp := [][]string{
+		{`null`},
+		{`false`},
+		{`1.5`, `2`},
+		{`"a"`, `"b"`},
+		{`[0]`, `[1]`},
+		{`{"x": 1}`},
+		{`{3}`},
+	}
+
+	groups := s.GroupedSortedSlice()
+	act := make([][]string, len(groups))
+	for i, group := range groups {
+		for _, term := range group {
+			act[i] = append(act[i], term.String())
+		}
+	}
+	if !slices.EqualFunc(exp, act, slices.Equal) {
+		t.Fatalf("Expected %v but got %v", exp, act)
+	}
+
+	// Appending to a group must not affect the next one.
+	_ = append(groups[0], StringTerm("x"))
+	if groups[1][0].String() != "false" {
+		t.Fatal("Expected groups not to share capacity")
+	}
+
+	if groups := NewSet().GroupedSortedSlice(); len(groups) != 0 {
+		t.Fatalf("Expected no groups for empty set but got %v", groups)
+	}
+}
+
 func TestSetCopy(t *testing.T) {
 	orig := MustParseTerm("{1,2,3}")
 	cpy := orig.Copy()
@@ -959,6 +1176,53 @@ func TestSetCopy(t *testing.T) {
 	}
 }
 
+func TestSetSliceSorted(t *testing.T) {
+	terms := []*Term{
+		NullTerm(),
+		BooleanTerm(false),
+		IntNumberTerm(-1),
+		NumberTerm("2.5"),
+		IntNumberTerm(10),
+		StringTerm("a"),
+		StringTerm("b"),
+		VarTerm("x"),
+		ArrayTerm(IntNumberTerm(1)),
+		ObjectTerm(Item(StringTerm("a"), IntNumberTerm(1))),
+		SetTerm(IntNumberTerm(1)),
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for range 10 {
+		shuffled := make([]*Term, len(terms))
+		copy(shuffled, terms)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+		s := NewSet()
+		for _, x := range shuffled {
+			s.Add(x)
+		}
+		result := s.Slice()
+		if !termSliceEqual(result, terms) {
+			t.Fatalf("expected %v but got %v", terms, result)
+		}
+		if again := s.Slice(); &again[0] != &result[0] {
+			t.Fatal("expected sorted slice to be cached")
+		}
+
+		// Adding elements invalidates the cached order, but must not reorder
+		// the slice returned before.
+		s.Add(StringTerm("0"))
+		s.Add(IntNumberTerm(0))
+		updated := s.Slice()
+		if !slices.IsSortedFunc(updated, TermValueCompare) || len(updated) != len(terms)+2 {
+			t.Fatalf("expected sorted slice with added elements but got %v", updated)
+		}
+		if !termSliceEqual(result, terms) {
+			t.Fatalf("expected previous slice to be unchanged but got %v", result)
+		}
+	}
+}
+
 // Constructs a set, and then has several reader goroutines attempt to
 // concurrently iterate across it. This should pretty consistently
 // hit a race condition around sorting the underlying key slice if
@@ -1046,6 +1310,37 @@ func TestObjectConcurrentReads(t *testing.T) {
 	wg.Wait()
 }
 
+func TestArraySorted(t *testing.T) {
+	arr := MustParseTerm(`["b", 2, 1.0, "a", 1, 2.0, null, 1e0]`).Value.(*Array)
+	orig := arr.String()
+
+	sorted := arr.Sorted()
+
+	// Equal numbers keep their original spelling and relative order.
+	exp := `[null, 1.0, 1, 1e0, 2, 2.0, "a", "b"]`
+	if act := sorted.String(); act != exp {
+		t.Fatalf("Expected %v but got %v", exp, act)
+	}
+	if act := arr.String(); act != orig {
+		t.Fatalf("Expected source array to be unchanged but got %v", act)
+	}
+
+	// The sorted array must be consistent with a freshly built one.
+	fresh := NewArray(sorted.elems...)
+	if !slices.Equal(sorted.hashs, fresh.hashs) || sorted.Hash() != fresh.Hash() || sorted.IsGround() != fresh.IsGround() {
+		t.Fatal("Expected element hashes to be reordered with the elements")
+	}
+
+	// Modifying the sorted array must not affect the source.
+	sorted.Set(0, StringTerm("x"))
+	if act := arr.String(); act != orig {
+		t.Fatalf("Expected source array to be unchanged but got %v", act)
+	}
+	if !slices.Equal(arr.hashs, NewArray(arr.elems...).hashs) {
+		t.Fatal("Expected source array hashes to be unchanged")
+	}
+}
+
 func TestArrayOperations(t *testing.T) {
 	arr := MustParseTerm(`[1,2,3,4]`).Value.(*Array)
 
@@ -1559,7 +1854,7 @@ func TestLazyObjectCompare(t *testing.T) {
 	if exp, act := 1, x.Compare(NewObject()); exp != act {
 		t.Errorf("expected Compare() => %v, got %v", exp, act)
 	}
-	assertForced(t, x, true)
+	assertForced(t, x, false)
 }
 
 func assertForced(t *testing.T, x Object, forced bool) {
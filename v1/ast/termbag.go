@@ -0,0 +1,75 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "slices"
+
+// TermBag is a multiset of terms that counts how often each term was added.
+// Terms are considered the same if their values are equal according to
+// ValueEqual, so 1 and 1.0 are counted together. The zero value is an empty
+// bag ready to use.
+type TermBag struct {
+	entries map[int][]*TermBagEntry
+	len     int
+}
+
+// TermBagEntry is a distinct term of a TermBag along with the number of times
+// it was added. Term is the first of the equal terms that was added.
+type TermBagEntry struct {
+	Term  *Term
+	Count int
+}
+
+// Add adds t to b.
+func (b *TermBag) Add(t *Term) {
+	hash := t.Hash()
+	if e := b.get(hash, t); e != nil {
+		e.Count++
+		return
+	}
+	if b.entries == nil {
+		b.entries = map[int][]*TermBagEntry{}
+	}
+	b.entries[hash] = append(b.entries[hash], &TermBagEntry{Term: t, Count: 1})
+	b.len++
+}
+
+// Count returns the number of times t, or any term equal to it, was added to
+// b.
+func (b *TermBag) Count(t *Term) int {
+	if e := b.get(t.Hash(), t); e != nil {
+		return e.Count
+	}
+	return 0
+}
+
+// Len returns the number of distinct terms in b.
+func (b *TermBag) Len() int {
+	return b.len
+}
+
+// SortedEntries returns the distinct terms of b with their counts, ordered by
+// Compare.
+func (b *TermBag) SortedEntries() []TermBagEntry {
+	entries := make([]TermBagEntry, 0, b.len)
+	for _, bucket := range b.entries {
+		for _, e := range bucket {
+			entries = append(entries, *e)
+		}
+	}
+	slices.SortFunc(entries, func(x, y TermBagEntry) int {
+		return Compare(x.Term, y.Term)
+	})
+	return entries
+}
+
+func (b *TermBag) get(hash int, t *Term) *TermBagEntry {
+	for _, e := range b.entries[hash] {
+		if ValueEqual(e.Term.Value, t.Value) {
+			return e
+		}
+	}
+	return nil
+}
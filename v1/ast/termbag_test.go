@@ -0,0 +1,56 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTermBag(t *testing.T) {
+	var b TermBag
+	for _, s := range []string{`"b"`, `1`, `{1, 2}`, `1.0`, `null`, `"b"`, `1e0`, `{2.0, 1}`, `2`, `[1]`, `"a"`} {
+		b.Add(MustParseTerm(s))
+	}
+
+	if b.Len() != 7 {
+		t.Fatalf("Expected 7 distinct terms but got %d", b.Len())
+	}
+
+	tests := []struct {
+		term string
+		exp  int
+	}{
+		{`1`, 3},
+		{`10e-1`, 3},
+		{`2.0`, 1},
+		{`"b"`, 2},
+		{`{1.0, 2.0}`, 2},
+		{`[1.0]`, 1},
+		{`3`, 0},
+		{`"1"`, 0},
+	}
+	for _, tc := range tests {
+		if act := b.Count(MustParseTerm(tc.term)); act != tc.exp {
+			t.Errorf("Expected count %d for %v but got %d", tc.exp, tc.term, act)
+		}
+	}
+
+	entries := b.SortedEntries()
+	act := make([]string, len(entries))
+	for i, e := range entries {
+		act[i] = fmt.Sprintf("%v: %d", e.Term, e.Count)
+	}
+	exp := []string{`null: 1`, `1: 3`, `2: 1`, `"a": 1`, `"b": 2`, `[1]: 1`, `{1, 2}: 2`}
+	if strings.Join(act, "\n") != strings.Join(exp, "\n") {
+		t.Fatalf("Expected:\n%v\n\nGot:\n%v", strings.Join(exp, "\n"), strings.Join(act, "\n"))
+	}
+
+	var empty TermBag
+	if empty.Count(NullTerm()) != 0 || empty.Len() != 0 || len(empty.SortedEntries()) != 0 {
+		t.Fatal("Expected zero value to be an empty bag")
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "container/heap"
+
+// TermHeap is a priority queue of terms ordered by Compare. It implements
+// heap.Interface so that it can be used with the functions of container/heap,
+// but PushTerm, PopTerm and Peek are easier to use. Push and Pop are only
+// meant to be called by container/heap.
+type TermHeap struct {
+	terms []*Term
+	max   bool
+}
+
+// NewTermHeap returns an empty TermHeap. If min is true, PopTerm returns the
+// least term first, otherwise the greatest.
+func NewTermHeap(min bool) *TermHeap {
+	return &TermHeap{max: !min}
+}
+
+// Len returns the number of terms in h.
+func (h *TermHeap) Len() int {
+	return len(h.terms)
+}
+
+// Less reports whether the term at index i should be popped before the term
+// at index j.
+func (h *TermHeap) Less(i, j int) bool {
+	if h.max {
+		return Compare(h.terms[j], h.terms[i]) < 0
+	}
+	return Compare(h.terms[i], h.terms[j]) < 0
+}
+
+// Swap swaps the terms at indices i and j.
+func (h *TermHeap) Swap(i, j int) {
+	h.terms[i], h.terms[j] = h.terms[j], h.terms[i]
+}
+
+// Push appends x, which must be a *Term, to h. Use PushTerm instead.
+func (h *TermHeap) Push(x any) {
+	h.terms = append(h.terms, x.(*Term))
+}
+
+// Pop removes and returns the last term of h. Use PopTerm instead.
+func (h *TermHeap) Pop() any {
+	n := len(h.terms) - 1
+	t := h.terms[n]
+	h.terms[n] = nil
+	h.terms = h.terms[:n]
+	return t
+}
+
+// PushTerm adds t to h.
+func (h *TermHeap) PushTerm(t *Term) {
+	heap.Push(h, t)
+}
+
+// PopTerm removes and returns the least term of h, or the greatest term for
+// max heaps. It returns nil if h is empty.
+func (h *TermHeap) PopTerm() *Term {
+	if len(h.terms) == 0 {
+		return nil
+	}
+	return heap.Pop(h).(*Term)
+}
+
+// Peek returns the term that PopTerm would return next without removing it,
+// or nil if h is empty.
+func (h *TermHeap) Peek() *Term {
+	if len(h.terms) == 0 {
+		return nil
+	}
+	return h.terms[0]
+}
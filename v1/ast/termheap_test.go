@@ -0,0 +1,66 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"container/heap"
+	"math/rand"
+	"testing"
+)
+
+func TestTermHeap(t *testing.T) {
+	ordered := []*Term{
+		NullTerm(),
+		BooleanTerm(false),
+		BooleanTerm(true),
+		IntNumberTerm(-1),
+		NumberTerm("1.5"),
+		StringTerm("a"),
+		VarTerm("x"),
+		MustParseTerm("data.a"),
+		ArrayTerm(IntNumberTerm(1)),
+		ObjectTerm(Item(StringTerm("a"), IntNumberTerm(1))),
+		SetTerm(IntNumberTerm(1)),
+		MustParseTerm("[x | x := 1]"),
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for _, isMin := range []bool{true, false} {
+		h := NewTermHeap(isMin)
+		for _, i := range rng.Perm(len(ordered)) {
+			h.PushTerm(ordered[i])
+		}
+		if h.Len() != len(ordered) {
+			t.Fatalf("Expected %d terms but got %d", len(ordered), h.Len())
+		}
+		for i := range ordered {
+			exp := ordered[i]
+			if !isMin {
+				exp = ordered[len(ordered)-1-i]
+			}
+			if peek := h.Peek(); Compare(peek, exp) != 0 {
+				t.Fatalf("Expected Peek to return %v but got %v", exp, peek)
+			}
+			if act := h.PopTerm(); Compare(act, exp) != 0 {
+				t.Fatalf("Expected PopTerm to return %v but got %v", exp, act)
+			}
+		}
+		if h.Peek() != nil || h.PopTerm() != nil {
+			t.Fatal("Expected empty heap to return nil")
+		}
+	}
+}
+
+func TestTermHeapContainerHeap(t *testing.T) {
+	h := NewTermHeap(true)
+	for _, s := range []string{`3`, `1`, `"a"`, `2`} {
+		heap.Push(h, MustParseTerm(s))
+	}
+	for _, exp := range []string{`1`, `2`, `3`, `"a"`} {
+		if act := heap.Pop(h).(*Term); act.String() != exp {
+			t.Fatalf("Expected %v but got %v", exp, act)
+		}
+	}
+}
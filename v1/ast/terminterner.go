@@ -0,0 +1,43 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+// TermInterner maps equal terms to a single canonical term, so that repeated
+// constants can share their storage. Terms are considered equal if their
+// values are equal according to ValueEqual, so 1 and 1.0 are interned as the
+// same term. The zero value is an empty interner ready to use.
+//
+// Canonical terms are shared by everyone who interned an equal term, so they
+// must not be modified, and their locations are those of the first term
+// interned.
+type TermInterner struct {
+	terms map[uint64][]*Term
+	len   int
+}
+
+// Intern returns the canonical term for t: the first term interned in i that
+// is equal to t, or t itself if there is none. A nil term is returned as is.
+func (i *TermInterner) Intern(t *Term) *Term {
+	if t == nil {
+		return nil
+	}
+	hash := Hash(t.Value)
+	for _, c := range i.terms[hash] {
+		if ValueEqual(c.Value, t.Value) {
+			return c
+		}
+	}
+	if i.terms == nil {
+		i.terms = map[uint64][]*Term{}
+	}
+	i.terms[hash] = append(i.terms[hash], t)
+	i.len++
+	return t
+}
+
+// Len returns the number of canonical terms in i.
+func (i *TermInterner) Len() int {
+	return i.len
+}
@@ -0,0 +1,112 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func TestTermInterner(t *testing.T) {
+	var i TermInterner
+	if i.Intern(nil) != nil {
+		t.Fatal("Expected nil for nil term")
+	}
+
+	values := []string{`"a"`, `1`, `{"a": [1, {2, 3}]}`, `null`, `[1, 2]`, `{1, 2}`}
+	canonical := make([]*Term, len(values))
+	for n := range 100 {
+		for j, s := range values {
+			term := MustParseTerm(s)
+			act := i.Intern(term)
+			if n == 0 {
+				if act != term {
+					t.Fatalf("Expected first %v to be canonical", term)
+				}
+				canonical[j] = act
+			} else if act != canonical[j] {
+				t.Fatalf("Expected %v to be interned as the first equal term", term)
+			}
+		}
+	}
+	if i.Len() != len(values) {
+		t.Fatalf("Expected %d canonical terms but got %d", len(values), i.Len())
+	}
+
+	tests := []struct {
+		term string
+		exp  int
+	}{
+		{`1.0`, 1},
+		{`1e0`, 1},
+		{`{"a": [1.0, {3, 2}]}`, 2},
+		{`{2.0, 1}`, 5},
+	}
+	for _, tc := range tests {
+		if act := i.Intern(MustParseTerm(tc.term)); act != canonical[tc.exp] {
+			t.Errorf("Expected %v to be interned as %v but got %v", tc.term, canonical[tc.exp], act)
+		}
+	}
+
+	for _, s := range []string{`2`, `"1"`, `[2, 1]`, `{"a": [1]}`} {
+		term := MustParseTerm(s)
+		if act := i.Intern(term); act != term {
+			t.Errorf("Expected %v to be canonical but got %v", term, act)
+		}
+	}
+	if i.Len() != len(values)+4 {
+		t.Fatalf("Expected %d canonical terms but got %d", len(values)+4, i.Len())
+	}
+}
+
+func TestTermInternerCustomValue(t *testing.T) {
+	var i TermInterner
+	a := NewTerm(customValue{0, 1})
+	if act := i.Intern(a); act != a {
+		t.Fatalf("Expected %v to be canonical but got %v", a, act)
+	}
+	if act := i.Intern(NewTerm(customValue{0, 1})); act != a {
+		t.Fatalf("Expected equal custom value to be interned as %v but got %v", a, act)
+	}
+	for _, v := range []Value{customValue{0, 2}, customValue{1, 1}} {
+		term := NewTerm(v)
+		if act := i.Intern(term); act != term {
+			t.Errorf("Expected %v to be canonical but got %v", term, act)
+		}
+	}
+	if Hash(customValue{0, 1}) != Hash(customValue{0, 1}) {
+		t.Fatal("Expected equal custom values to hash the same")
+	}
+}
+
+func BenchmarkTermInterner(b *testing.B) {
+	const n = 1000
+	for _, intern := range []bool{false, true} {
+		b.Run(fmt.Sprintf("intern=%v", intern), func(b *testing.B) {
+			var retained uint64
+			for range b.N {
+				var i TermInterner
+				terms := make([]*Term, n)
+				runtime.GC()
+				var before, after runtime.MemStats
+				runtime.ReadMemStats(&before)
+				for j := range terms {
+					term := MustParseTerm(`{"role": "admin", "actions": ["read", "write"], "limit": 100}`)
+					if intern {
+						term = i.Intern(term)
+					}
+					terms[j] = term
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				retained += after.HeapAlloc - before.HeapAlloc
+				runtime.KeepAlive(terms)
+				runtime.KeepAlive(&i)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N*n), "retained-B/term")
+		})
+	}
+}